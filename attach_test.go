@@ -0,0 +1,54 @@
+package jira
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateAttachFailed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jira-attach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "log.txt")
+	if err := ioutil.WriteFile(file, []byte("disk full"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	document := filepath.Join(dir, "issue.yml")
+	if err := ioutil.WriteFile(document, []byte("fields:\n  project:\n    key: GOJIRA\n  issuetype:\n    name: Task\n  summary: disk full\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := newTestJira(t, map[string]testJiraHandler{
+		"GET /rest/api/2/issue/createmeta": testJiraRespond(200, map[string]interface{}{
+			"projects": []interface{}{map[string]interface{}{
+				"issuetypes": []interface{}{map[string]interface{}{
+					"fields": map[string]interface{}{
+						"project":   map[string]interface{}{"name": "Project", "schema": map[string]interface{}{"type": "project"}},
+						"issuetype": map[string]interface{}{"name": "Issue Type", "schema": map[string]interface{}{"type": "issuetype"}},
+						"summary":   map[string]interface{}{"name": "Summary", "schema": map[string]interface{}{"type": "string"}},
+					},
+				}},
+			}},
+		}),
+		"POST /rest/api/2/issue":                      testJiraRespond(201, map[string]interface{}{"key": "GOJIRA-1"}),
+		"POST /rest/api/2/issue/GOJIRA-1/attachments": testJiraRespond(500, nil),
+	})
+	defer server.Close()
+
+	c := server.cli(map[string]interface{}{
+		"file": document, "noedit": true, "edit": false, "quiet": true, "attach": []interface{}{file},
+	})
+	if err := c.CmdCreate(); err == nil {
+		t.Error("expected the failed upload to be an error")
+	}
+	if created := server.sent("POST /rest/api/2/issue"); len(created) != 1 {
+		t.Errorf("expected 1 issue to be created, got %d", len(created))
+	}
+	if uploads := server.sent("POST /rest/api/2/issue/GOJIRA-1/attachments"); len(uploads) != 1 {
+		t.Errorf("expected 1 upload, got %d", len(uploads))
+	}
+}
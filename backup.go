@@ -0,0 +1,481 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// backupState records the last backup of a project in DIR/backup.json, so
+// later backups only fetch the issues updated since
+type backupState struct {
+	Project string `json:"project"`
+	Updated string `json:"updated,omitempty"`
+}
+
+// restoreState records the progress of a restore in DIR/restore-PROJECT.json,
+// so an interrupted restore can be run again without duplicating anything
+type restoreState struct {
+	// Keys maps the original keys to the keys of the restored issues
+	Keys map[string]string `json:"keys"`
+	// Linked are the original keys whose links have been recreated
+	Linked map[string]bool `json:"linked,omitempty"`
+	// Moved are the original keys whose status has been restored
+	Moved map[string]bool `json:"moved,omitempty"`
+}
+
+// writeJSON will write the data as indented JSON so backups are greppable
+func writeJSON(file string, data interface{}) error {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, append(content, '\n'), 0644); err != nil {
+		err = fmt.Errorf("Failed to write %s: %s", file, err)
+		log.Errorf("%s", err)
+		return err
+	}
+	return nil
+}
+
+func readJSON(file string, data interface{}) error {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, data)
+}
+
+// CmdBackup will write the project, with its components and versions, to
+// DIR/project.json and each issue to DIR/issues/KEY.json with its
+// changelog, comments, worklogs and remote links.  With the
+// 'with-attachments' option the attachments are downloaded to
+// DIR/attachments/KEY/.  When DIR has a previous backup only the issues
+// updated since are fetched again, unless the 'full' option is set.
+func (c *Cli) CmdBackup(project string, dir string) error {
+	log.Debugf("backup called")
+	if project == "" {
+		err := fmt.Errorf("Missing required 'project' option")
+		log.Errorf("%s", err)
+		return err
+	}
+	if err := mkdir(filepath.Join(dir, "issues")); err != nil {
+		return err
+	}
+
+	projectData := map[string]interface{}{}
+	for name, path := range map[string]string{"project": "", "components": "/components", "versions": "/versions"} {
+		uri := fmt.Sprintf("%s/rest/api/2/project/%s%s", c.endpoint, project, path)
+		data, err := responseToJSON(c.get(uri))
+		if err != nil {
+			return err
+		}
+		projectData[name] = data
+	}
+	if err := writeJSON(filepath.Join(dir, "project.json"), projectData); err != nil {
+		return err
+	}
+
+	stateFile := filepath.Join(dir, "backup.json")
+	state := backupState{}
+	readJSON(stateFile, &state)
+	jql := NewJQLBuilder().Where(JQLEquals("project", JQLString(project)))
+	if state.Project == project && state.Updated != "" && !c.getOptBool("full", false) {
+		if updated, err := time.Parse(jiraTimeFormat, state.Updated); err == nil {
+			// the query is in the timezone of the jira user, so look back
+			// a day and skip the issues that have not changed
+			clause, _ := JQLCompare("updated", ">=", JQLString(updated.Add(-24*time.Hour).Format("2006/01/02 15:04")))
+			jql.Where(clause)
+		}
+	}
+	query := jql.OrderBy("updated", false).String()
+
+	written, unchanged := 0, 0
+	err := c.searchAll(query, []string{"updated"}, 0, 0, func(issues []interface{}) error {
+		for _, i := range issues {
+			found, _ := i.(map[string]interface{})
+			key, _ := found["key"].(string)
+			fields, _ := found["fields"].(map[string]interface{})
+			updated, _ := fields["updated"].(string)
+			if updated > state.Updated {
+				state.Updated = updated
+			}
+
+			file := filepath.Join(dir, "issues", key+".json")
+			previous := map[string]interface{}{}
+			if err := readJSON(file, &previous); err == nil {
+				if prevFields, ok := previous["fields"].(map[string]interface{}); ok && prevFields["updated"] == updated {
+					unchanged++
+					continue
+				}
+			}
+			issueData, err := c.backupIssue(key, dir)
+			if err != nil {
+				return err
+			}
+			if err := writeJSON(file, issueData); err != nil {
+				return err
+			}
+			written++
+			if !c.GetOptBool("quiet", false) {
+				fmt.Printf("OK %s %s\n", key, file)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	state.Project = project
+	if err := writeJSON(stateFile, state); err != nil {
+		return err
+	}
+	log.Infof("Backed up %d issues to %s, %d unchanged", written, dir, unchanged)
+	return nil
+}
+
+// backupIssue will return the issue with its changelog, comments, worklogs
+// and remote links, downloading the attachments when requested
+func (c *Cli) backupIssue(key string, dir string) (map[string]interface{}, error) {
+	data, err := c.viewIssue(key, "changelog")
+	if err != nil {
+		return nil, err
+	}
+	issueData, _ := data.(map[string]interface{})
+
+	comments, err := c.issueComments(key)
+	if err != nil {
+		return nil, err
+	}
+	issueData["comments"] = comments
+
+	worklogs, err := c.ViewIssueWorkLogs(key)
+	if err != nil {
+		return nil, err
+	}
+	issueData["worklogs"], _ = worklogs.(map[string]interface{})["worklogs"]
+
+	if remoteLinks, err := c.RemoteLinks(key); err == nil {
+		issueData["remotelinks"] = remoteLinks
+	} else {
+		log.Warningf("Failed to get remote links for %s: %s", key, err)
+	}
+
+	if c.getOptBool("with-attachments", false) {
+		fields, _ := issueData["fields"].(map[string]interface{})
+		attachments, _ := fields["attachment"].([]interface{})
+		for _, a := range attachments {
+			attachment, _ := a.(map[string]interface{})
+			if err := c.backupAttachment(key, attachment, dir); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return issueData, nil
+}
+
+// backupAttachment will download the attachment to
+// DIR/attachments/KEY/ID-FILENAME unless it was downloaded before
+func (c *Cli) backupAttachment(key string, attachment map[string]interface{}, dir string) error {
+	attachmentDir := filepath.Join(dir, "attachments", key)
+	if err := mkdir(attachmentDir); err != nil {
+		return err
+	}
+	filename, _ := attachment["filename"].(string)
+	// never trust the server to give us a path
+	file := filepath.Join(attachmentDir, fmt.Sprintf("%v-%s", attachment["id"], filepath.Base(filename)))
+	if size, ok := attachment["size"].(float64); ok {
+		if stat, err := os.Stat(file); err == nil && stat.Size() == int64(size) {
+			return nil
+		}
+	}
+	content, _ := attachment["content"].(string)
+	resp, err := c.download(content)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		err := fmt.Errorf("Unexpected Response From GET: %s", resp.Status)
+		log.Errorf("%s", err)
+		return err
+	}
+	fh, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		log.Errorf("Failed to open %s for writing: %s", file, err)
+		return err
+	}
+	defer fh.Close()
+	if _, err := io.Copy(fh, resp.Body); err != nil {
+		log.Errorf("Failed to write %s: %s", file, err)
+		return err
+	}
+	return nil
+}
+
+// backupIssues will read the issues of a backup, with the parents before
+// the subtasks and otherwise in the order they were created
+func backupIssues(dir string) ([]map[string]interface{}, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "issues", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	issues := []map[string]interface{}{}
+	for _, file := range files {
+		issueData := map[string]interface{}{}
+		if err := readJSON(file, &issueData); err != nil {
+			err = fmt.Errorf("Failed to read %s: %s", file, err)
+			log.Errorf("%s", err)
+			return nil, err
+		}
+		issues = append(issues, issueData)
+	}
+	order := func(issueData map[string]interface{}) (bool, int) {
+		id, _ := strconv.Atoi(fmt.Sprintf("%v", issueData["id"]))
+		return issueField(issueData, "parent", "key") != "", id
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		iSubtask, iID := order(issues[i])
+		jSubtask, jID := order(issues[j])
+		if iSubtask != jSubtask {
+			return jSubtask
+		}
+		return iID < jID
+	})
+	return issues, nil
+}
+
+// CmdRestore will recreate the issues of a backup in the project given by the
+// 'project' option.  The components and versions of the backed up project are
+// created first when missing.  Each issue is created with the fields allowed
+// by the create metadata, then a comment records the original key, and the
+// comments, worklogs and downloaded attachments are added.  Once all the
+// issues exist their links are recreated with the new keys, and each issue
+// is transitioned to its original status.  The original to new key mapping,
+// and which issues have been linked and transitioned, is kept in
+// DIR/restore-PROJECT.json so an interrupted restore can be run again
+// without duplicating issues or links.
+func (c *Cli) CmdRestore(dir string) error {
+	log.Debugf("restore called")
+	project := strings.ToUpper(c.getOptString("project", ""))
+	if project == "" {
+		err := fmt.Errorf("Missing required 'project' option")
+		log.Errorf("%s", err)
+		return err
+	}
+	issues, err := backupIssues(dir)
+	if err != nil {
+		return err
+	}
+	if err := c.restoreProject(project, dir); err != nil {
+		return err
+	}
+
+	stateFile := filepath.Join(dir, fmt.Sprintf("restore-%s.json", project))
+	state := &restoreState{}
+	readJSON(stateFile, state)
+	if state.Keys == nil {
+		state.Keys = map[string]string{}
+	}
+	if state.Linked == nil {
+		state.Linked = map[string]bool{}
+	}
+	if state.Moved == nil {
+		state.Moved = map[string]bool{}
+	}
+	dryrun := c.getOptBool("dryrun", false)
+
+	for _, issueData := range issues {
+		source, _ := issueData["key"].(string)
+		if _, ok := state.Keys[source]; ok {
+			log.Debugf("%s already restored as %s", source, state.Keys[source])
+			continue
+		}
+		key, err := c.restoreIssue(issueData, project, state.Keys)
+		if err != nil {
+			return err
+		}
+		if key == "" {
+			// dryrun
+			continue
+		}
+		// the key is recorded before anything else is added, so a failure
+		// below never leads to the issue being created again
+		state.Keys[source] = key
+		if err := writeJSON(stateFile, state); err != nil {
+			return err
+		}
+		if err := c.restoreIssueContent(issueData, key, dir); err != nil {
+			return err
+		}
+		if !c.GetOptBool("quiet", false) {
+			fmt.Printf("OK %s %s %s/browse/%s\n", source, key, c.endpoint, key)
+		}
+	}
+	if dryrun {
+		return nil
+	}
+
+	mapped := func(key string) string {
+		if newKey, ok := state.Keys[key]; ok {
+			return newKey
+		}
+		return key
+	}
+	for _, issueData := range issues {
+		source, _ := issueData["key"].(string)
+		if _, ok := state.Keys[source]; !ok {
+			continue
+		}
+		if !state.Linked[source] {
+			linked := true
+			fields, _ := issueData["fields"].(map[string]interface{})
+			links, _ := fields["issuelinks"].([]interface{})
+			for _, l := range links {
+				link, _ := l.(map[string]interface{})
+				other, ok := link["outwardIssue"].(map[string]interface{})
+				if !ok {
+					// only the outward side, the inward side is the
+					// same link seen from the other issue
+					continue
+				}
+				otherKey := fmt.Sprintf("%v", other["key"])
+				linkType, _ := link["type"].(map[string]interface{})
+				name, _ := linkType["name"].(string)
+				if err := c.linkIssues(name, mapped(source), mapped(otherKey)); err != nil {
+					log.Warningf("Unable to link %s to %s: %s", mapped(source), mapped(otherKey), err)
+					linked = false
+				}
+			}
+			state.Linked[source] = linked
+		}
+		if status := issueField(issueData, "status", "name"); !state.Moved[source] && status != "" {
+			quiet := c.GetOptBool("quiet", false)
+			c.opts["quiet"] = true
+			if err := c.moveTo(mapped(source), status, false); err != nil {
+				log.Warningf("Unable to transition %s to %s: %s", mapped(source), status, err)
+			} else {
+				state.Moved[source] = true
+			}
+			c.opts["quiet"] = quiet
+		}
+		if err := writeJSON(stateFile, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreProject will create the components and versions of the backed up
+// project that are missing in the target project
+func (c *Cli) restoreProject(project string, dir string) error {
+	projectData := map[string]interface{}{}
+	if err := readJSON(filepath.Join(dir, "project.json"), &projectData); err != nil {
+		log.Warningf("No project data in %s, skipping components and versions", dir)
+		return nil
+	}
+	for _, kind := range []string{"components", "versions"} {
+		uri := fmt.Sprintf("%s/rest/api/2/project/%s/%s", c.endpoint, project, kind)
+		data, err := responseToJSON(c.get(uri))
+		if err != nil {
+			return err
+		}
+		existing := map[string]bool{}
+		current, _ := data.([]interface{})
+		for _, item := range current {
+			existing[fmt.Sprintf("%v", item.(map[string]interface{})["name"])] = true
+		}
+		backedUp, _ := projectData[kind].([]interface{})
+		for _, item := range backedUp {
+			value, _ := item.(map[string]interface{})
+			name := fmt.Sprintf("%v", value["name"])
+			if existing[name] {
+				continue
+			}
+			body := map[string]interface{}{
+				"name":    name,
+				"project": project,
+			}
+			for _, property := range []string{"description", "released", "archived", "releaseDate", "startDate"} {
+				if v, ok := value[property]; ok {
+					body[property] = v
+				}
+			}
+			uri := fmt.Sprintf("%s/rest/api/2/component", c.endpoint)
+			if kind == "versions" {
+				uri = fmt.Sprintf("%s/rest/api/2/version", c.endpoint)
+			}
+			if _, err := c.postCreated(uri, body); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// restoreIssue will create the issue from the backup, returning the new key
+func (c *Cli) restoreIssue(issueData map[string]interface{}, project string, keys map[string]string) (string, error) {
+	issuetype := issueField(issueData, "issuetype", "name")
+	fields, _, err := c.cloneIssueFields(issueData, project, issuetype)
+	if err != nil {
+		return "", err
+	}
+	if parent := issueField(issueData, "parent", "key"); parent != "" {
+		if newParent, ok := keys[parent]; ok {
+			parent = newParent
+		}
+		fields["parent"] = map[string]interface{}{"key": parent}
+	}
+	json, err := jsonEncode(map[string]interface{}{
+		"fields": fields,
+	})
+	if err != nil {
+		return "", err
+	}
+	return c.createIssue(json)
+}
+
+// restoreIssueContent will add a comment with the original key, then the
+// comments, worklogs and downloaded attachments of the backed up issue to
+// the restored issue
+func (c *Cli) restoreIssueContent(issueData map[string]interface{}, key string, dir string) error {
+	source, _ := issueData["key"].(string)
+	if err := c.addComment(key, fmt.Sprintf("Restored from %s", source)); err != nil {
+		return err
+	}
+	comments, _ := issueData["comments"].([]interface{})
+	for _, cm := range comments {
+		comment, _ := cm.(map[string]interface{})
+		author, _ := comment["author"].(map[string]interface{})
+		body := fmt.Sprintf("%v wrote on %v:\n\n%v", author["displayName"], comment["created"], comment["body"])
+		if err := c.addComment(key, body); err != nil {
+			return err
+		}
+	}
+	worklogs, _ := issueData["worklogs"].([]interface{})
+	for _, w := range worklogs {
+		worklog, _ := w.(map[string]interface{})
+		body := map[string]interface{}{
+			"started":          worklog["started"],
+			"timeSpentSeconds": worklog["timeSpentSeconds"],
+		}
+		author, _ := worklog["author"].(map[string]interface{})
+		body["comment"] = fmt.Sprintf("%v: %v", author["displayName"], worklog["comment"])
+		uri := fmt.Sprintf("%s/rest/api/2/issue/%s/worklog?adjustEstimate=leave", c.endpoint, key)
+		if _, err := c.postCreated(uri, body); err != nil {
+			return err
+		}
+	}
+	attachments, _ := filepath.Glob(filepath.Join(dir, "attachments", source, "*"))
+	if err := c.attachFiles(key, attachments); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package jira
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRestoreInterrupted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jira-restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := mkdir(filepath.Join(dir, "issues")); err != nil {
+		t.Fatal(err)
+	}
+	for id, issueData := range map[string]map[string]interface{}{
+		"1": {
+			"key": "OLD-1",
+			"fields": map[string]interface{}{
+				"summary":   "blocker",
+				"issuetype": map[string]interface{}{"name": "Task"},
+				"status":    map[string]interface{}{"name": "Done"},
+				"issuelinks": []interface{}{map[string]interface{}{
+					"type":         map[string]interface{}{"name": "Blocks"},
+					"outwardIssue": map[string]interface{}{"key": "OLD-2"},
+				}},
+			},
+		},
+		"2": {
+			"key": "OLD-2",
+			"fields": map[string]interface{}{
+				"summary":   "blocked",
+				"issuetype": map[string]interface{}{"name": "Task"},
+				"status":    map[string]interface{}{"name": "Done"},
+			},
+		},
+	} {
+		issueData["id"] = id
+		if err := writeJSON(filepath.Join(dir, "issues", issueData["key"].(string)+".json"), issueData); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	created := 0
+	failComment := true
+	server := newTestJira(t, map[string]testJiraHandler{
+		"GET /rest/api/2/issue/createmeta": testJiraRespond(200, map[string]interface{}{
+			"projects": []interface{}{map[string]interface{}{
+				"issuetypes": []interface{}{map[string]interface{}{
+					"fields": map[string]interface{}{
+						"summary": map[string]interface{}{"name": "Summary", "schema": map[string]interface{}{"type": "string"}},
+					},
+				}},
+			}},
+		}),
+		"POST /rest/api/2/issue": func(*http.Request, interface{}) (int, interface{}) {
+			created++
+			return 201, map[string]interface{}{"key": fmt.Sprintf("NEW-%d", created)}
+		},
+		"POST /rest/api/2/issue/NEW-1/comment": testJiraRespond(201, nil),
+		"POST /rest/api/2/issue/NEW-2/comment": func(*http.Request, interface{}) (int, interface{}) {
+			if failComment {
+				return 500, nil
+			}
+			return 201, nil
+		},
+		"POST /rest/api/2/issueLink": testJiraRespond(201, nil),
+		// the restored issues are already in their original status
+		"GET /rest/api/2/issue/NEW-1": testJiraRespond(200, map[string]interface{}{
+			"key":    "NEW-1",
+			"fields": map[string]interface{}{"status": map[string]interface{}{"name": "Done"}},
+		}),
+		"GET /rest/api/2/issue/NEW-2": testJiraRespond(200, map[string]interface{}{
+			"key":    "NEW-2",
+			"fields": map[string]interface{}{"status": map[string]interface{}{"name": "Done"}},
+		}),
+	})
+	defer server.Close()
+
+	// the comment on the second issue fails after it was created
+	c := server.cli(map[string]interface{}{"project": "NEW", "quiet": true})
+	if err := c.CmdRestore(dir); err == nil {
+		t.Fatal("expected the restore to fail")
+	}
+	state := &restoreState{}
+	if err := readJSON(filepath.Join(dir, "restore-NEW.json"), state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Keys["OLD-1"] != "NEW-1" || state.Keys["OLD-2"] != "NEW-2" {
+		t.Errorf("expected both issues in the mapping, got %v", state.Keys)
+	}
+	if len(server.sent("POST /rest/api/2/issueLink")) != 0 {
+		t.Errorf("expected no links before all the issues are restored")
+	}
+
+	// running it again only restores the links and statuses
+	failComment = false
+	if err := c.CmdRestore(dir); err != nil {
+		t.Fatal(err)
+	}
+	if created != 2 {
+		t.Errorf("expected 2 issues to be created, got %d", created)
+	}
+	links := server.sent("POST /rest/api/2/issueLink")
+	if len(links) != 1 {
+		t.Fatalf("expected 1 link, got %d", len(links))
+	}
+	link := links[0].(map[string]interface{})
+	if inward := link["inwardIssue"].(map[string]interface{})["key"]; inward != "NEW-1" {
+		t.Errorf("expected the link from NEW-1, got %v", inward)
+	}
+	if outward := link["outwardIssue"].(map[string]interface{})["key"]; outward != "NEW-2" {
+		t.Errorf("expected the link to NEW-2, got %v", outward)
+	}
+
+	state = &restoreState{}
+	if err := readJSON(filepath.Join(dir, "restore-NEW.json"), state); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"OLD-1", "OLD-2"} {
+		if !state.Linked[key] || !state.Moved[key] {
+			t.Errorf("expected %s to be linked and moved, got %#v", key, state)
+		}
+	}
+
+	// and a third run has nothing left to do
+	if err := c.CmdRestore(dir); err != nil {
+		t.Fatal(err)
+	}
+	if created != 2 || len(server.sent("POST /rest/api/2/issueLink")) != 1 {
+		t.Errorf("expected nothing to be restored again")
+	}
+}
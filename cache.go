@@ -0,0 +1,377 @@
+package jira
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// issueCache is the offline copy of the issues of an endpoint, kept under
+// ~/.jira.d/cache/HOST.  Each issue is stored in issues/KEY.json and
+// index.json records the queries that have been synced, so the issues can be
+// found again offline and the issues no query returns any more are pruned.
+type issueCache struct {
+	dir     string
+	Queries map[string]*cachedQuery `json:"queries"`
+}
+
+// cachedQuery is a JQL query that has been synced
+type cachedQuery struct {
+	// Synced is when the query was last synced
+	Synced string `json:"synced"`
+	// Updated is the latest update of the issues, later syncs only fetch
+	// the issues updated since
+	Updated string `json:"updated,omitempty"`
+	// Issues are the keys of the issues the query returned, in order
+	Issues []string `json:"issues"`
+}
+
+var orderByRegexp = regexp.MustCompile(`(?i)\s+order\s+by\s+.*$`)
+
+func (c *Cli) issueCache() (*issueCache, error) {
+	cache := &issueCache{
+		dir:     filepath.Join(homedir(), ".jira.d", "cache", c.endpoint.Host),
+		Queries: map[string]*cachedQuery{},
+	}
+	file := filepath.Join(cache.dir, "index.json")
+	if err := readJSON(file, cache); err != nil && !os.IsNotExist(err) {
+		err = fmt.Errorf("Failed to read %s: %s", file, err)
+		log.Errorf("%s", err)
+		return nil, err
+	}
+	return cache, nil
+}
+
+func (cache *issueCache) save() error {
+	if err := mkdir(cache.dir); err != nil {
+		return err
+	}
+	return writeJSON(filepath.Join(cache.dir, "index.json"), cache)
+}
+
+func (cache *issueCache) issueFile(key string) string {
+	return filepath.Join(cache.dir, "issues", key+".json")
+}
+
+// synced will return when the issue was last known to be current, which is
+// the latest sync of the queries that returned it
+func (cache *issueCache) synced(key string) string {
+	synced := ""
+	for _, query := range cache.Queries {
+		for _, k := range query.Issues {
+			if k == key && query.Synced > synced {
+				synced = query.Synced
+			}
+		}
+	}
+	return synced
+}
+
+// prune will remove the issues that none of the queries return
+func (cache *issueCache) prune() error {
+	wanted := map[string]bool{}
+	for _, query := range cache.Queries {
+		for _, key := range query.Issues {
+			wanted[key] = true
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(cache.dir, "issues", "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		key := strings.TrimSuffix(filepath.Base(file), ".json")
+		if !wanted[key] {
+			log.Debugf("Pruning %s from the cache", key)
+			if err := os.Remove(file); err != nil {
+				log.Warningf("Failed to remove %s: %s", file, err)
+			}
+		}
+	}
+	return nil
+}
+
+// CmdSync will copy the issues matching the 'query' option (or the query
+// generated from the other query options) to the offline cache, with the
+// 'all' option every query synced before is synced again.  Only the issues
+// updated since the last sync of a query are fetched, unless the 'full'
+// option is set, and the issues the query no longer returns are dropped.
+// Issues that no synced query returns are removed from the cache.
+func (c *Cli) CmdSync() error {
+	log.Debugf("sync called")
+	cache, err := c.issueCache()
+	if err != nil {
+		return err
+	}
+	queries := []string{}
+	if c.getOptBool("all", false) {
+		for query := range cache.Queries {
+			queries = append(queries, query)
+		}
+	} else {
+		query, err := c.findQuery()
+		if err != nil {
+			return err
+		}
+		queries = append(queries, query)
+	}
+	for _, query := range queries {
+		if err := c.syncQuery(cache, query); err != nil {
+			return err
+		}
+	}
+	return cache.prune()
+}
+
+func (c *Cli) syncQuery(cache *issueCache, query string) error {
+	if err := mkdir(filepath.Join(cache.dir, "issues")); err != nil {
+		return err
+	}
+	cached, ok := cache.Queries[query]
+	incremental := ok && cached.Updated != "" && !c.getOptBool("full", false)
+	if !ok {
+		cached = &cachedQuery{}
+	}
+	synced := time.Now().Format(jiraTimeFormat)
+
+	search := query
+	unordered := orderByRegexp.ReplaceAllString(query, "")
+	if incremental {
+		if updated, err := time.Parse(jiraTimeFormat, cached.Updated); err == nil {
+			// the query is in the timezone of the jira user, so look back
+			// a day to be sure not to miss any updates
+			since, _ := JQLCompare("updated", ">=", JQLString(updated.Add(-24*time.Hour).Format("2006/01/02 15:04")))
+			search = NewJQLBuilder().
+				Where(JQLRaw(unordered)).
+				Where(since).
+				String()
+		}
+	}
+
+	keys := []string{}
+	err := c.searchAll(search, []string{"*all"}, 0, 0, func(issues []interface{}) error {
+		for _, i := range issues {
+			issueData, _ := i.(map[string]interface{})
+			key, _ := issueData["key"].(string)
+			fields, _ := issueData["fields"].(map[string]interface{})
+			if updated, _ := fields["updated"].(string); updated > cached.Updated {
+				cached.Updated = updated
+			}
+			if err := writeJSON(cache.issueFile(key), issueData); err != nil {
+				return err
+			}
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if incremental {
+		// the issues cached before that were not updated may still have
+		// stopped matching (like when they were resolved), so check which
+		// of them the query still returns and drop the others
+		found := map[string]bool{}
+		for _, key := range keys {
+			found[key] = true
+		}
+		unchanged := []string{}
+		for _, key := range cached.Issues {
+			if !found[key] {
+				unchanged = append(unchanged, key)
+			}
+		}
+		matching, err := c.matchingKeys(unordered, unchanged)
+		if err != nil {
+			return err
+		}
+		issues := []string{}
+		for _, key := range cached.Issues {
+			if found[key] || matching[key] {
+				issues = append(issues, key)
+				delete(found, key)
+			} else {
+				log.Debugf("%s no longer matches: %s", key, query)
+			}
+		}
+		for _, key := range keys {
+			if found[key] {
+				issues = append(issues, key)
+			}
+		}
+		cached.Issues = issues
+	} else {
+		cached.Issues = keys
+	}
+	cached.Synced = synced
+	cache.Queries[query] = cached
+	if err := cache.save(); err != nil {
+		return err
+	}
+	if !c.GetOptBool("quiet", false) {
+		fmt.Printf("OK %d issues updated, %d cached for: %s\n", len(keys), len(cached.Issues), query)
+	}
+	return nil
+}
+
+// matchingKeys will return which of the keys the query still returns.  The
+// keys are checked a page at a time with 'key in (...)', and the query is
+// only validated with warnings so keys of deleted issues are not an error.
+func (c *Cli) matchingKeys(query string, keys []string) (map[string]bool, error) {
+	matching := map[string]bool{}
+	for len(keys) > 0 {
+		page := keys
+		if len(page) > searchPageSize {
+			page = page[:searchPageSize]
+		}
+		keys = keys[len(page):]
+
+		values := []JQLValue{}
+		for _, key := range page {
+			values = append(values, JQLString(key))
+		}
+		json, err := jsonEncode(map[string]interface{}{
+			"jql":           NewJQLBuilder().Where(JQLRaw(query)).Where(JQLIn("key", values...)).String(),
+			"startAt":       0,
+			"maxResults":    len(page),
+			"fields":        []string{"key"},
+			"validateQuery": "warn",
+		})
+		if err != nil {
+			return nil, err
+		}
+		uri := fmt.Sprintf("%s/rest/api/2/search", c.endpoint)
+		data, err := responseToJSON(c.post(uri, json))
+		if err != nil {
+			return nil, err
+		}
+		results, _ := data.(map[string]interface{})
+		issues, _ := results["issues"].([]interface{})
+		for _, i := range issues {
+			issueData, _ := i.(map[string]interface{})
+			if key, ok := issueData["key"].(string); ok {
+				matching[key] = true
+			}
+		}
+	}
+	return matching, nil
+}
+
+// cachedIssue will return the issue from the offline cache, with the time
+// it was last synced as "cached"
+func (c *Cli) cachedIssue(issue string) (interface{}, error) {
+	cache, err := c.issueCache()
+	if err != nil {
+		return nil, err
+	}
+	issueData := map[string]interface{}{}
+	if err := readJSON(cache.issueFile(strings.ToUpper(issue)), &issueData); err != nil {
+		err := fmt.Errorf("%s is not in the offline cache, use 'jira sync' to add it", issue)
+		log.Errorf("%s", err)
+		return nil, err
+	}
+	issueData["cached"] = cache.synced(strings.ToUpper(issue))
+	return issueData, nil
+}
+
+// cachedSearch will return the results of the query from the offline cache
+// in the same form as the search api, with the time the issues were last
+// synced as "cached".  A query that has been synced returns the issues the
+// server returned, other queries are evaluated against all the cached
+// issues.
+func (c *Cli) cachedSearch(query string, startAt int, maxResults int) (interface{}, error) {
+	cache, err := c.issueCache()
+	if err != nil {
+		return nil, err
+	}
+	cached, ok := cache.Queries[query]
+	if !ok {
+		return c.evalCachedSearch(cache, query, startAt, maxResults)
+	}
+	issues := []interface{}{}
+	for i, key := range cached.Issues {
+		if i < startAt || maxResults > 0 && len(issues) >= maxResults {
+			continue
+		}
+		issueData := map[string]interface{}{}
+		if err := readJSON(cache.issueFile(key), &issueData); err != nil {
+			log.Warningf("%s is missing from the offline cache", key)
+			continue
+		}
+		issues = append(issues, issueData)
+	}
+	return map[string]interface{}{
+		"startAt":    startAt,
+		"maxResults": maxResults,
+		"total":      float64(len(cached.Issues)),
+		"issues":     issues,
+		"cached":     cached.Synced,
+	}, nil
+}
+
+// jqlEnv will return the environment to evaluate JQL queries in
+func (c *Cli) jqlEnv() *JQLEnv {
+	return &JQLEnv{
+		User: c.getOptString("user", ""),
+		Now:  time.Now(),
+		Resolve: func(name string) string {
+			return c.resolveField(name, nil)
+		},
+	}
+}
+
+// evalCachedSearch will evaluate the query against all the cached issues
+func (c *Cli) evalCachedSearch(cache *issueCache, query string, startAt int, maxResults int) (interface{}, error) {
+	jql, err := ParseJQL(query)
+	if err != nil {
+		log.Errorf("%s", err)
+		return nil, err
+	}
+	env := c.jqlEnv()
+	files, err := filepath.Glob(filepath.Join(cache.dir, "issues", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	matches := []interface{}{}
+	synced := ""
+	for _, file := range files {
+		issueData := map[string]interface{}{}
+		if err := readJSON(file, &issueData); err != nil {
+			log.Warningf("Ignoring invalid cache file %s: %s", file, err)
+			continue
+		}
+		ok, err := jql.Match(env, issueData)
+		if err != nil {
+			log.Errorf("%s", err)
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		matches = append(matches, issueData)
+		// the results are only as current as the oldest issue
+		key, _ := issueData["key"].(string)
+		if s := cache.synced(key); synced == "" || s < synced {
+			synced = s
+		}
+	}
+	jql.Sort(env, matches)
+
+	issues := []interface{}{}
+	for i, issueData := range matches {
+		if i >= startAt && (maxResults <= 0 || len(issues) < maxResults) {
+			issues = append(issues, issueData)
+		}
+	}
+	return map[string]interface{}{
+		"startAt":    startAt,
+		"maxResults": maxResults,
+		"total":      float64(len(matches)),
+		"issues":     issues,
+		"cached":     synced,
+	}, nil
+}
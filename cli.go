@@ -5,7 +5,9 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httputil"
@@ -16,6 +18,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/kballard/go-shellquote"
@@ -35,6 +38,8 @@ type Cli struct {
 	opts       map[string]interface{}
 	cookieFile string
 	ua         *http.Client
+	document   []byte
+	cmdline    map[string]interface{}
 }
 
 // New creates go-jira client object
@@ -81,10 +86,46 @@ func New(opts map[string]interface{}) *Cli {
 	return cli
 }
 
+// cookieLock serializes updates to the cookie file for commands that make
+// concurrent requests
+var cookieLock sync.Mutex
+
+// loginLock serializes logging in again after a 401 for commands that make
+// concurrent requests, logins counts the successful logins so requests that
+// failed while someone else was logging in just retry instead of prompting
+// for the password again
+var (
+	loginLock sync.Mutex
+	logins    int
+)
+
+func loginCount() int {
+	loginLock.Lock()
+	defer loginLock.Unlock()
+	return logins
+}
+
+// relogin will login again unless another request has already done so since
+// the count of logins was taken with loginCount
+func (c *Cli) relogin(count int) error {
+	loginLock.Lock()
+	defer loginLock.Unlock()
+	if logins != count {
+		return nil
+	}
+	if err := c.CmdLogin(); err != nil {
+		return err
+	}
+	logins++
+	return nil
+}
+
 func (c *Cli) saveCookies(resp *http.Response) {
 	if _, ok := resp.Header["Set-Cookie"]; !ok {
 		return
 	}
+	cookieLock.Lock()
+	defer cookieLock.Unlock()
 
 	cookies := resp.Cookies()
 	for _, cookie := range cookies {
@@ -158,11 +199,12 @@ func (c *Cli) delete(uri string) (resp *http.Response, err error) {
 	method := "DELETE"
 	req, _ := http.NewRequest(method, uri, nil)
 	log.Infof("%s %s", req.Method, req.URL.String())
+	count := loginCount()
 	if resp, err = c.makeRequest(req); err != nil {
 		return nil, err
 	}
 	if resp.StatusCode == 401 {
-		if err = c.CmdLogin(); err != nil {
+		if err = c.relogin(count); err != nil {
 			return nil, err
 		}
 		req, _ = http.NewRequest(method, uri, nil)
@@ -176,11 +218,12 @@ func (c *Cli) makeRequestWithContent(method string, uri string, content string)
 	req, _ := http.NewRequest(method, uri, buffer)
 
 	log.Infof("%s %s", req.Method, req.URL.String())
+	count := loginCount()
 	if resp, err = c.makeRequest(req); err != nil {
 		return nil, err
 	}
 	if resp.StatusCode == 401 {
-		if err = c.CmdLogin(); err != nil {
+		if err = c.relogin(count); err != nil {
 			return nil, err
 		}
 		req, _ = http.NewRequest(method, uri, bytes.NewBufferString(content))
@@ -198,11 +241,84 @@ func (c *Cli) get(uri string) (resp *http.Response, err error) {
 		log.Debugf("%s", logBuffer)
 	}
 
+	count := loginCount()
+	if resp, err = c.makeRequest(req); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == 401 {
+		if err := c.relogin(count); err != nil {
+			return nil, err
+		}
+		return c.makeRequest(req)
+	}
+	return resp, err
+}
+
+// upload will POST the given files as a multipart/form-data request, which
+// is what jira requires for adding attachments to an issue
+func (c *Cli) upload(uri string, files []string) (resp *http.Response, err error) {
+	newRequest := func() (*http.Request, error) {
+		buffer := bytes.NewBuffer(make([]byte, 0))
+		writer := multipart.NewWriter(buffer)
+		for _, file := range files {
+			fh, err := os.Open(file)
+			if err != nil {
+				log.Errorf("Failed to open %s: %s", file, err)
+				return nil, err
+			}
+			part, err := writer.CreateFormFile("file", filepath.Base(file))
+			if err == nil {
+				_, err = io.Copy(part, fh)
+			}
+			fh.Close()
+			if err != nil {
+				log.Errorf("Failed to read %s: %s", file, err)
+				return nil, err
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		req, _ := http.NewRequest("POST", uri, buffer)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		// jira will reject the upload with XSRF check failures without this header
+		req.Header.Set("X-Atlassian-Token", "no-check")
+		return req, nil
+	}
+
+	req, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("%s %s", req.Method, req.URL.String())
+	count := loginCount()
+	if resp, err = c.makeRequest(req); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == 401 {
+		if err = c.relogin(count); err != nil {
+			return nil, err
+		}
+		if req, err = newRequest(); err != nil {
+			return nil, err
+		}
+		return c.makeRequest(req)
+	}
+	return resp, err
+}
+
+// download will GET the given uri without requesting a json response, the
+// caller is responsible for streaming the response body where it is needed
+func (c *Cli) download(uri string) (resp *http.Response, err error) {
+	req, _ := http.NewRequest("GET", uri, nil)
+	req.Header.Set("Accept", "*/*")
+	log.Infof("%s %s", req.Method, req.URL.String())
+	count := loginCount()
 	if resp, err = c.makeRequest(req); err != nil {
 		return nil, err
 	}
 	if resp.StatusCode == 401 {
-		if err := c.CmdLogin(); err != nil {
+		if err := c.relogin(count); err != nil {
 			return nil, err
 		}
 		return c.makeRequest(req)
@@ -211,8 +327,14 @@ func (c *Cli) get(uri string) (resp *http.Response, err error) {
 }
 
 func (c *Cli) makeRequest(req *http.Request) (resp *http.Response, err error) {
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
+	// only default the content headers, some requests (like attachment
+	// uploads) need to send something other than json
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	if source, ok := c.opts["password-source"]; ok && !strings.HasSuffix(req.URL.Path, "/rest/auth/1/session") {
 		user, _ := c.opts["user"].(string)
@@ -251,7 +373,8 @@ func (c *Cli) makeRequest(req *http.Request) (resp *http.Response, err error) {
 		c.saveCookies(resp)
 	}
 	if log.IsEnabledFor(logging.DEBUG) {
-		out, _ := httputil.DumpResponse(resp, true)
+		// dont buffer binary attachment content into the debug log
+		out, _ := httputil.DumpResponse(resp, req.Header.Get("Accept") == "application/json")
 		log.Debugf("Response: %s", out)
 	}
 	return resp, nil
@@ -333,8 +456,17 @@ func (c *Cli) editTemplate(template string, tmpFilePrefix string, templateData m
 		os.Remove(tmpFileName)
 	}()
 
-	err = runTemplate(template, templateData, fh)
-	if err != nil {
+	if c.getOptString("file", "") != "" {
+		// a prepared document replaces the rendered template
+		document, err := c.fileDocument()
+		if err != nil {
+			return err
+		}
+		if _, err := fh.Write(document); err != nil {
+			log.Errorf("Failed to write %s: %s", tmpFileName, err)
+			return err
+		}
+	} else if err := runTemplate(template, templateData, fh); err != nil {
 		return err
 	}
 
@@ -359,6 +491,13 @@ func (c *Cli) editTemplate(template string, tmpFilePrefix string, templateData m
 		os.Remove(tmpFileNameOrig)
 	}()
 
+	// the overrides are already in the document when it is edited, so
+	// changes made in the editor win over them
+	var rendered map[string]interface{}
+	if editing {
+		rendered = renderedDocument(tmpFileNameOrig)
+	}
+
 	for true {
 		if editing {
 			shell, _ := shellquote.Split(editor)
@@ -412,19 +551,30 @@ func (c *Cli) editTemplate(template string, tmpFilePrefix string, templateData m
 			return fmt.Errorf("abort flag found in template, quiting")
 		}
 
-		if _, ok := templateData["meta"]; ok {
-			mf := templateData["meta"].(map[string]interface{})["fields"]
-			if f, ok := edited["fields"].(map[string]interface{}); ok {
-				for k := range f {
-					if _, ok := mf.(map[string]interface{})[k]; !ok {
-						err := fmt.Errorf("Field %s is not editable", k)
-						log.Errorf("%s", err)
-						if editing && promptYN("edit again?", true) {
-							continue
-						}
+		// a prepared document only needs the issue content, anything
+		// the command already knows (like the parent of a subtask) is
+		// filled in from the "defaults" when missing
+		if defaults, ok := templateData["defaults"].(map[string]interface{}); ok && c.getOptString("file", "") != "" {
+			mergeMissing(edited, defaults)
+		}
+
+		if meta, ok := templateData["meta"].(map[string]interface{}); ok {
+			metaFields := fieldMetaMap(meta)
+			problems := c.applyFieldOverrides(edited, rendered, metaFields)
+			problems = append(problems, coerceFields(edited, metaFields)...)
+			problems = append(problems, validateEdit(edited, metaFields)...)
+			if len(problems) > 0 {
+				for _, problem := range problems {
+					log.Errorf("%s", problem)
+				}
+				if editing && promptYN("edit again?", true) {
+					// show the problems at the top of the document
+					if err := ioutil.WriteFile(tmpFileName, errorComments(data, problems), 0600); err != nil {
 						return err
 					}
+					continue
 				}
+				return fmt.Errorf("Found %d invalid fields", len(problems))
 			}
 		}
 
@@ -444,6 +594,88 @@ func (c *Cli) editTemplate(template string, tmpFilePrefix string, templateData m
 	return nil
 }
 
+// renderedDocument will return the document in the file as it was before
+// editing, or nil if it can not be parsed
+func renderedDocument(file string) map[string]interface{} {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	rendered := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &rendered); err != nil {
+		return nil
+	}
+	fixed, err := yamlFixup(rendered)
+	if err != nil {
+		return nil
+	}
+	rendered, _ = fixed.(map[string]interface{})
+	return rendered
+}
+
+// fileDocument will return the YAML or JSON issue document from the 'file'
+// option, read from stdin when it is "-".  The document is only read once
+// so it can be used for each issue when editing several issues.
+func (c *Cli) fileDocument() ([]byte, error) {
+	if c.document != nil {
+		return c.document, nil
+	}
+	file := c.getOptString("file", "")
+	var content []byte
+	var err error
+	if file == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		err = fmt.Errorf("Failed to read document from %s: %s", file, err)
+		log.Errorf("%s", err)
+		return nil, err
+	}
+	c.document = content
+	return content, nil
+}
+
+// mergeMissing will copy the values from src that are missing in dst,
+// merging nested maps
+func mergeMissing(dst map[string]interface{}, src map[string]interface{}) {
+	for key, value := range src {
+		existing, ok := dst[key]
+		if !ok || existing == nil {
+			dst[key] = value
+			continue
+		}
+		dstMap, dstOK := existing.(map[string]interface{})
+		srcMap, srcOK := value.(map[string]interface{})
+		if dstOK && srcOK {
+			mergeMissing(dstMap, srcMap)
+		}
+	}
+}
+
+// fileDocumentField will return the property of a field (eg "project",
+// "key") from the document given by the 'file' option, or an empty string
+func (c *Cli) fileDocumentField(field string, property string) string {
+	if c.getOptString("file", "") == "" {
+		return ""
+	}
+	content, err := c.fileDocument()
+	if err != nil {
+		return ""
+	}
+	document := map[string]interface{}{}
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return ""
+	}
+	fixed, err := yamlFixup(document)
+	if err != nil {
+		return ""
+	}
+	document, _ = fixed.(map[string]interface{})
+	return issueField(document, field, property)
+}
+
 // Browse will open up your default browser to the provided issue
 func (c *Cli) Browse(issue string) error {
 	if val, ok := c.opts["browse"].(bool); ok && val {
@@ -478,8 +710,18 @@ func (c *Cli) ViewIssueWorkLogs(issue string) (interface{}, error) {
 
 // ViewIssue will return the details for the given issue id
 func (c *Cli) ViewIssue(issue string) (interface{}, error) {
+	return c.viewIssue(issue)
+}
+
+// viewIssue will return the details for the given issue, requesting the
+// "expand" option expansions along with any extra expansions provided.  With
+// the 'offline' option the issue is read from the offline cache.
+func (c *Cli) viewIssue(issue string, extra ...string) (interface{}, error) {
+	if c.getOptBool("offline", false) {
+		return c.cachedIssue(issue)
+	}
 	uri := fmt.Sprintf("%s/rest/api/2/issue/%s", c.endpoint, issue)
-	if x := c.expansions(); len(x) > 0 {
+	if x := c.expansions(extra...); len(x) > 0 {
 		uri = fmt.Sprintf("%s?expand=%s", uri, strings.Join(x, ","))
 	}
 
@@ -497,55 +739,226 @@ func (c *Cli) ViewIssue(issue string) (interface{}, error) {
 // Further it will restrict the fields being extracted from the jira
 // response with the 'queryfields' option
 func (c *Cli) FindIssues() (interface{}, error) {
-	var query string
-	var ok bool
-	// project = BAKERY and status not in (Resolved, Closed)
-	if query, ok = c.opts["query"].(string); !ok {
-		qbuff := bytes.NewBufferString("resolution = unresolved")
-		var project string
-		if project, ok = c.opts["project"].(string); !ok {
-			err := fmt.Errorf("Missing required arguments, either 'query' or 'project' are required")
-			log.Errorf("%s", err)
-			return nil, err
-		}
-		qbuff.WriteString(fmt.Sprintf(" AND project = '%s'", project))
+	query, err := c.findQuery()
+	if err != nil {
+		return nil, err
+	}
+	return c.search(query, c.queryFields(), c.opts["start_at"], c.opts["max_results"])
+}
 
-		if component, ok := c.opts["component"]; ok {
-			qbuff.WriteString(fmt.Sprintf(" AND component = '%s'", component))
-		}
+// FindAllIssues will page through the issues that match the options like
+// FindIssues, calling handler with the results of each page.  Up to the
+// 'max_results' option issues are returned, all of them when it is 0.
+func (c *Cli) FindAllIssues(fields []string, handler func(issues []interface{}) error) error {
+	query, err := c.findQuery()
+	if err != nil {
+		return err
+	}
+	startAt, _ := c.opts["start_at"].(int)
+	limit, _ := c.opts["max_results"].(int)
+	return c.searchAll(query, fields, startAt, limit, handler)
+}
 
-		if assignee, ok := c.opts["assignee"]; ok {
-			qbuff.WriteString(fmt.Sprintf(" AND assignee = '%s'", assignee))
+// searchAll will page through the results of the JQL query, calling handler
+// with the issues of each page, until limit issues are found (or all of
+// them when limit is 0)
+func (c *Cli) searchAll(query string, fields []string, startAt int, limit int, handler func(issues []interface{}) error) error {
+	for found := 0; limit <= 0 || found < limit; {
+		maxResults := limit - found
+		if limit <= 0 || maxResults > searchPageSize {
+			maxResults = searchPageSize
 		}
-
-		if issuetype, ok := c.opts["issuetype"]; ok {
-			qbuff.WriteString(fmt.Sprintf(" AND issuetype = '%s'", issuetype))
+		data, err := c.search(query, fields, startAt, maxResults)
+		if err != nil {
+			return err
+		}
+		results, _ := data.(map[string]interface{})
+		issues, _ := results["issues"].([]interface{})
+		if len(issues) == 0 {
+			break
+		}
+		if err := handler(issues); err != nil {
+			return err
+		}
+		found += len(issues)
+		startAt += len(issues)
+		if total, ok := results["total"].(float64); ok && startAt >= int(total) {
+			break
 		}
+	}
+	return nil
+}
 
-		if watcher, ok := c.opts["watcher"]; ok {
-			qbuff.WriteString(fmt.Sprintf(" AND watcher = '%s'", watcher))
+// searchPageSize is the number of issues requested at a time when paging
+// through search results, jira will return fewer if it has a lower limit
+const searchPageSize = 100
+
+// findQuery will return the 'query' option, or the JQL query generated
+// from the other query options
+func (c *Cli) findQuery() (string, error) {
+	if query, ok := c.opts["query"].(string); ok {
+		return query, nil
+	}
+	// the option values are quoted, so they can only ever be compared
+	// with their field
+	jql := NewJQLBuilder()
+	if !c.getOptBool("include-resolved", false) {
+		jql.Where(JQLEquals("resolution", JQLUnresolved))
+	}
+	filtered := false
+	if project, ok := c.opts["project"].(string); ok {
+		jql.Where(JQLEquals("project", JQLString(project)))
+		filtered = true
+	}
+	for _, option := range []string{"component", "assignee", "issuetype", "watcher", "reporter"} {
+		if value, ok := c.opts[option]; ok {
+			jql.Where(JQLEquals(option, JQLString(fmt.Sprintf("%v", value))))
+			filtered = true
+		}
+	}
+	for _, option := range [][2]string{
+		{"status", "status"},
+		{"status-category", "statusCategory"},
+		{"label", "labels"},
+		{"priority", "priority"},
+		{"fix-version", "fixVersion"},
+		{"epic", "Epic Link"},
+	} {
+		if values := c.queryValues(option[0]); len(values) > 0 {
+			jql.Where(JQLIn(option[1], jqlStrings(values)...))
+			filtered = true
 		}
+	}
+	// every text must match
+	for _, text := range c.queryValues("text") {
+		clause, _ := JQLCompare("text", "~", JQLString(text))
+		jql.Where(clause)
+		filtered = true
+	}
+	for _, option := range [][2]string{{"created-since", "created"}, {"updated-since", "updated"}} {
+		if value := c.getOptString(option[0], ""); value != "" {
+			since, err := jqlSince(value)
+			if err != nil {
+				return "", err
+			}
+			clause, _ := JQLCompare(option[1], ">=", since)
+			jql.Where(clause)
+			filtered = true
+		}
+	}
+	if values := c.queryValues("sprint"); len(values) > 0 {
+		clause, err := jqlSprints(values)
+		if err != nil {
+			log.Errorf("%s", err)
+			return "", err
+		}
+		jql.Where(clause)
+		filtered = true
+	}
+	if c.getOptBool("mine", false) {
+		currentUser, _ := JQLFunction("currentUser")
+		jql.Where(JQLEquals("assignee", currentUser))
+		filtered = true
+	}
+	if !filtered {
+		err := fmt.Errorf("Missing required arguments, either 'query', 'project' or another query option is required")
+		log.Errorf("%s", err)
+		return "", err
+	}
 
-		if reporter, ok := c.opts["reporter"]; ok {
-			qbuff.WriteString(fmt.Sprintf(" AND reporter = '%s'", reporter))
+	if sort, ok := c.opts["sort"].(string); ok && sort != "" {
+		if err := jql.SortBy(sort); err != nil {
+			log.Errorf("%s", err)
+			return "", err
 		}
+	}
+	return jql.String(), nil
+}
 
-		if sort, ok := c.opts["sort"]; ok && sort != "" {
-			qbuff.WriteString(fmt.Sprintf(" ORDER BY %s", sort))
+// queryValues will return the values of a query option, which may be
+// repeated and each may be a comma separated list
+func (c *Cli) queryValues(option string) []string {
+	values := []string{}
+	for _, value := range c.getOptStrings(option) {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
 		}
+	}
+	return values
+}
+
+func jqlStrings(values []string) []JQLValue {
+	jqlValues := make([]JQLValue, 0, len(values))
+	for _, value := range values {
+		jqlValues = append(jqlValues, JQLString(value))
+	}
+	return jqlValues
+}
 
-		query = qbuff.String()
+// jqlSince will return the JQL date for a duration ago (like "7d") or a
+// date (like "2017-01-31")
+func jqlSince(value string) (JQLValue, error) {
+	if _, err := parseRelativeDuration(value); err == nil {
+		return JQLString("-" + strings.TrimSpace(value)), nil
+	}
+	since, err := parseSince(value)
+	if err != nil {
+		return JQLValue{}, err
+	}
+	return JQLString(since.Format("2006/01/02 15:04")), nil
+}
+
+// jqlSprints will return the clause matching any of the sprints, which are
+// sprint names or ids, or "current", "future" and "closed" for the open,
+// future and closed sprints
+func jqlSprints(values []string) (JQLClause, error) {
+	clauses := []JQLClause{}
+	names := []JQLValue{}
+	for _, value := range values {
+		function, ok := map[string]string{
+			"current": "openSprints",
+			"future":  "futureSprints",
+			"closed":  "closedSprints",
+		}[strings.ToLower(value)]
+		if !ok {
+			names = append(names, JQLString(value))
+			continue
+		}
+		clause, err := JQLInFunction("sprint", function)
+		if err != nil {
+			return JQLClause{}, err
+		}
+		clauses = append(clauses, clause)
+	}
+	if len(names) > 0 {
+		clauses = append(clauses, JQLIn("sprint", names...))
 	}
+	return JQLOr(clauses...), nil
+}
 
+// queryFields will return the fields from the 'queryfields' option
+func (c *Cli) queryFields() []string {
 	fields := []string{"summary"}
 	if qf, ok := c.opts["queryfields"].(string); ok {
 		fields = strings.Split(qf, ",")
 	}
+	return fields
+}
 
+// search will POST the JQL query to the search api, or return the results
+// from the offline cache with the 'offline' option
+func (c *Cli) search(query string, fields []string, startAt interface{}, maxResults interface{}) (interface{}, error) {
+	if c.getOptBool("offline", false) {
+		start, _ := startAt.(int)
+		max, _ := maxResults.(int)
+		return c.cachedSearch(query, start, max)
+	}
 	json, err := jsonEncode(map[string]interface{}{
 		"jql":        query,
-		"startAt":    c.opts["start_at"],
-		"maxResults": c.opts["max_results"],
+		"startAt":    startAt,
+		"maxResults": maxResults,
 		"fields":     fields,
 		"expand":     c.expansions(),
 	})
@@ -623,6 +1036,27 @@ func (c *Cli) getOptString(optName string, dflt string) string {
 	return dflt
 }
 
+// SetCommandLineOptions records which of the options were given on the
+// command line rather than read from the config files.  Some options are only
+// taken from the command line, like the project to clone an issue to.
+func (c *Cli) SetCommandLineOptions(opts map[string]interface{}) {
+	c.cmdline = opts
+}
+
+// getCmdlineOptString will extract the string value of an option given on
+// the command line, otherwise return the provided default.  Without
+// SetCommandLineOptions all of the options are used.
+func (c *Cli) getCmdlineOptString(optName string, dflt string) string {
+	opts := c.cmdline
+	if opts == nil {
+		opts = c.opts
+	}
+	if val, ok := opts[optName].(string); ok {
+		return val
+	}
+	return dflt
+}
+
 // GetOptBool will extract the boolean value from the Client object options
 // otherwise return the provided default\
 func (c *Cli) GetOptBool(optName string, dflt bool) bool {
@@ -636,11 +1070,68 @@ func (c *Cli) getOptBool(optName string, dflt bool) bool {
 	return dflt
 }
 
-// expansions returns a comma-separated list of values for field expansion
-func (c *Cli) expansions() []string {
+// getOptStrings will extract a list of strings from the Cli object options,
+// the value may be a list (from the command line or config files) or
+// a single comma separated string
+func (c *Cli) getOptStrings(optName string) []string {
+	switch val := c.opts[optName].(type) {
+	case []string:
+		return val
+	case []interface{}:
+		vals := make([]string, 0, len(val))
+		for _, v := range val {
+			vals = append(vals, fmt.Sprintf("%v", v))
+		}
+		return vals
+	case string:
+		if val == "" {
+			return nil
+		}
+		return strings.Split(val, ",")
+	}
+	return nil
+}
+
+// localExpansions are the 'expand' option values handled by go-jira rather
+// than jira, they are not sent with the request
+var localExpansions = map[string]bool{
+	"remotelinks": true,
+}
+
+// expansions returns the values for field expansion from the 'expand' option,
+// any extra expansions are appended if not already requested
+func (c *Cli) expansions(extra ...string) []string {
 	var expansions []string
 	if x, ok := c.opts["expand"].(string); ok {
-		expansions = strings.Split(x, ",")
+		for _, e := range strings.Split(x, ",") {
+			if !localExpansions[e] {
+				expansions = append(expansions, e)
+			}
+		}
+	}
+	for _, x := range extra {
+		found := false
+		for _, e := range expansions {
+			if e == x {
+				found = true
+				break
+			}
+		}
+		if !found {
+			expansions = append(expansions, x)
+		}
 	}
 	return expansions
 }
+
+// expanded will return true when the 'expand' option names the expansion
+func (c *Cli) expanded(name string) bool {
+	if x, ok := c.opts["expand"].(string); ok {
+		for _, e := range strings.Split(x, ",") {
+			if e == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,406 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// fields that are never copied from a source issue, they are either set
+// explicitly for the new issue or recreated separately
+var uncopiedFields = map[string]bool{
+	"project":    true,
+	"issuetype":  true,
+	"parent":     true,
+	"attachment": true,
+	"issuelinks": true,
+	"subtasks":   true,
+}
+
+// custom field types that can be read but not written back in the same form
+var uncopiedCustomTypes = map[string]bool{
+	"com.pyxis.greenhopper.jira:gh-sprint":    true,
+	"com.pyxis.greenhopper.jira:gh-lexo-rank": true,
+}
+
+// mapFields will copy the source issue fields that are allowed by the target
+// create metadata.  Values are reduced to their identifying properties, and
+// when the target field has allowedValues the source values are matched up
+// by id, name or value.  The names of any source fields that could not be
+// carried over are returned.
+func mapFields(source map[string]interface{}, meta map[string]interface{}) (map[string]interface{}, []string) {
+	fields := map[string]interface{}{}
+	dropped := []string{}
+	metaFields, _ := meta["fields"].(map[string]interface{})
+
+	for name, value := range source {
+		if value == nil || uncopiedFields[name] {
+			continue
+		}
+		fieldMeta, ok := metaFields[name].(map[string]interface{})
+		if !ok {
+			if !isEmptyValue(value) {
+				dropped = append(dropped, name)
+			}
+			continue
+		}
+		if schema, ok := fieldMeta["schema"].(map[string]interface{}); ok {
+			if custom, ok := schema["custom"].(string); ok && uncopiedCustomTypes[custom] {
+				dropped = append(dropped, name)
+				continue
+			}
+		}
+		if name == "timetracking" {
+			if tt, ok := value.(map[string]interface{}); ok {
+				estimates := map[string]interface{}{}
+				for _, k := range []string{"originalEstimate", "remainingEstimate"} {
+					if v, ok := tt[k]; ok {
+						estimates[k] = v
+					}
+				}
+				if len(estimates) > 0 {
+					fields[name] = estimates
+				}
+			}
+			continue
+		}
+
+		allowed, _ := fieldMeta["allowedValues"].([]interface{})
+		mapped, ok := mapFieldValue(value, allowed)
+		if !ok {
+			dropped = append(dropped, name)
+			continue
+		}
+		if !isEmptyValue(mapped) {
+			fields[name] = mapped
+		}
+	}
+	return fields, dropped
+}
+
+func mapFieldValue(value interface{}, allowed []interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		values := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if mapped, ok := mapFieldValue(item, allowed); ok {
+				values = append(values, mapped)
+			}
+		}
+		if len(v) > 0 && len(values) == 0 {
+			return nil, false
+		}
+		return values, true
+	case map[string]interface{}:
+		if len(allowed) > 0 {
+			if match := findAllowedValue(v, allowed); match != nil {
+				return identifyingValue(match), true
+			}
+			return nil, false
+		}
+		return identifyingValue(v), true
+	default:
+		return v, true
+	}
+}
+
+// findAllowedValue will match the value to an allowed value by id first,
+// then by key, name or value
+func findAllowedValue(value map[string]interface{}, allowed []interface{}) map[string]interface{} {
+	for _, key := range []string{"id", "key", "name", "value"} {
+		want, ok := value[key]
+		if !ok {
+			continue
+		}
+		for _, a := range allowed {
+			if av, ok := a.(map[string]interface{}); ok && av[key] == want {
+				return av
+			}
+		}
+	}
+	return nil
+}
+
+// identifyingValue will strip a field value object down to the properties
+// that jira uses to identify the value on create/edit
+func identifyingValue(value map[string]interface{}) map[string]interface{} {
+	reduced := map[string]interface{}{}
+	for _, key := range []string{"id", "key", "name", "value"} {
+		if v, ok := value[key]; ok {
+			reduced[key] = v
+		}
+	}
+	if child, ok := value["child"].(map[string]interface{}); ok {
+		reduced["child"] = identifyingValue(child)
+	}
+	if len(reduced) == 0 {
+		return value
+	}
+	return reduced
+}
+
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// cloneIssueFields will return the fields from the source issue data that can
+// be used to create a new issue of the given project and issuetype
+func (c *Cli) cloneIssueFields(sourceData map[string]interface{}, project, issuetype string) (map[string]interface{}, map[string]interface{}, error) {
+	metaData, err := c.createIssueMetaData(project, issuetype)
+	if err != nil {
+		return nil, nil, err
+	}
+	meta, ok := metaData.(map[string]interface{})
+	if !ok {
+		err := fmt.Errorf("Unable to get create metadata for project '%s' issuetype '%s'", project, issuetype)
+		log.Errorf("%s", err)
+		return nil, nil, err
+	}
+	sourceFields, _ := sourceData["fields"].(map[string]interface{})
+	fields, dropped := mapFields(sourceFields, meta)
+	if len(dropped) > 0 {
+		log.Warningf("%s fields not allowed for %s %s, skipping: %s", sourceData["key"], project, issuetype, strings.Join(dropped, ", "))
+	}
+	fields["project"] = map[string]interface{}{"key": project}
+	fields["issuetype"] = map[string]interface{}{"name": issuetype}
+	return fields, meta, nil
+}
+
+func issueField(issueData map[string]interface{}, field string, property string) string {
+	if fields, ok := issueData["fields"].(map[string]interface{}); ok {
+		if value, ok := fields[field].(map[string]interface{}); ok {
+			if v, ok := value[property].(string); ok {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// CmdClone will create a copy of the given issue.  The target project and
+// issuetype default to the source issue's, and can be changed with the
+// 'project' and 'issuetype' options given on the command line.  Only fields
+// allowed by the target create metadata are copied.  The result is opened in
+// the "create" template for editing, with the source values as the defaults,
+// and the copied fields the template does not show are added when the clone
+// is created.  With the 'with-subtasks' and 'with-links' options the subtasks
+// and issue links of the source are recreated on the clone.  The clone is
+// linked to the source with the 'cloneLinkType' link type (default:
+// "Cloners").
+func (c *Cli) CmdClone(issue string) error {
+	log.Debugf("clone called")
+	data, err := c.ViewIssue(issue)
+	if err != nil {
+		return err
+	}
+	sourceData, ok := data.(map[string]interface{})
+	if !ok || sourceData["key"] == nil {
+		err := fmt.Errorf("Issue %s not found", issue)
+		log.Errorf("%s", err)
+		return err
+	}
+	source := sourceData["key"].(string)
+
+	// a project or issuetype from the config files is a default for new
+	// issues, a clone stays with the source unless told otherwise
+	project := strings.ToUpper(c.getCmdlineOptString("project", issueField(sourceData, "project", "key")))
+	issuetype := c.getCmdlineOptString("issuetype", issueField(sourceData, "issuetype", "name"))
+
+	fields, meta, err := c.cloneIssueFields(sourceData, project, issuetype)
+	if err != nil {
+		return err
+	}
+	if parent := issueField(sourceData, "parent", "key"); parent != "" {
+		fields["parent"] = map[string]interface{}{"key": parent}
+	}
+
+	cloneData := map[string]interface{}{
+		"source":    sourceData,
+		"meta":      meta,
+		"overrides": cloneOverrides(c.opts, sourceData, project, issuetype),
+		"defaults": map[string]interface{}{
+			"fields": fields,
+		},
+	}
+
+	sanitizedType := strings.ToLower(strings.Replace(issuetype, " ", "", -1))
+	return c.editTemplate(
+		c.getTemplate(fmt.Sprintf("create-%s", sanitizedType)),
+		fmt.Sprintf("%s-clone-", source),
+		cloneData,
+		func(json string) error {
+			json, err := addMissingFields(json, fields)
+			if err != nil {
+				return err
+			}
+			key, err := c.createIssue(json)
+			if err != nil || key == "" {
+				return err
+			}
+			withLinks, withSubtasks := c.getOptBool("with-links", false), c.getOptBool("with-subtasks", false)
+			if _, err := c.cloneRelations(sourceData, key, project, withLinks, withSubtasks); err != nil {
+				return err
+			}
+			link := fmt.Sprintf("%s/browse/%s", c.endpoint, key)
+			c.Browse(key)
+			c.SaveData(map[string]string{
+				"issue": key,
+				"link":  link,
+			})
+			if !c.GetOptBool("quiet", false) {
+				fmt.Printf("OK %s %s\n", key, link)
+			}
+			return nil
+		},
+	)
+}
+
+// cloneOverrides will return the template overrides for the create template
+// with the values of the source issue, the 'override' option (-o) takes
+// precedence
+func cloneOverrides(opts map[string]interface{}, sourceData map[string]interface{}, project, issuetype string) map[string]interface{} {
+	overrides := map[string]interface{}{}
+	for k, v := range opts {
+		overrides[k] = v
+	}
+	sourceFields, _ := sourceData["fields"].(map[string]interface{})
+	if summary, ok := sourceFields["summary"].(string); ok {
+		overrides["summary"] = summary
+	}
+	if description, ok := sourceFields["description"].(string); ok {
+		overrides["description"] = description
+	}
+	for _, field := range []string{"priority", "assignee", "reporter"} {
+		if name := issueField(sourceData, field, "name"); name != "" {
+			overrides[field] = name
+		}
+	}
+	if components, ok := sourceFields["components"].([]interface{}); ok && len(components) > 0 {
+		names := []string{}
+		for _, c := range components {
+			if component, ok := c.(map[string]interface{}); ok {
+				names = append(names, fmt.Sprintf("%v", component["name"]))
+			}
+		}
+		overrides["components"] = strings.Join(names, ",")
+	}
+	if o, ok := opts["override"].(map[string]interface{}); ok {
+		for k, v := range o {
+			overrides[k] = v
+		}
+	}
+	overrides["project"] = project
+	overrides["issuetype"] = issuetype
+	return overrides
+}
+
+// addMissingFields will add the fields the edited issue json does not have,
+// so fields the template does not show are still copied to the clone, while
+// those the template shows are left as edited
+func addMissingFields(issueJSON string, fields map[string]interface{}) (string, error) {
+	edited := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(issueJSON), &edited); err != nil {
+		return "", err
+	}
+	editedFields, ok := edited["fields"].(map[string]interface{})
+	if !ok {
+		editedFields = map[string]interface{}{}
+		edited["fields"] = editedFields
+	}
+	for name, value := range fields {
+		if _, ok := editedFields[name]; !ok {
+			editedFields[name] = value
+		}
+	}
+	return jsonEncode(edited)
+}
+
+// cloneRelations will link the clone back to the source, and recreate the
+// links and subtasks of the source with withLinks and withSubtasks.  The keys
+// of the cloned subtasks are returned by the key of their source subtask.
+func (c *Cli) cloneRelations(sourceData map[string]interface{}, key string, project string, withLinks bool, withSubtasks bool) (map[string]string, error) {
+	source := sourceData["key"].(string)
+	subtaskKeys := map[string]string{}
+	if linkType, reverse, err := c.FindIssueLinkType(c.GetOptString("cloneLinkType", "Cloners")); err != nil {
+		log.Warningf("Unable to link %s to %s: %s", key, source, err)
+	} else {
+		inward, outward := key, source
+		if reverse {
+			inward, outward = outward, inward
+		}
+		if err := c.linkIssues(linkType.Name, inward, outward); err != nil {
+			return nil, err
+		}
+	}
+
+	fields, _ := sourceData["fields"].(map[string]interface{})
+
+	if withLinks {
+		links, _ := fields["issuelinks"].([]interface{})
+		for _, l := range links {
+			link, ok := l.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			linkType, _ := link["type"].(map[string]interface{})
+			name, _ := linkType["name"].(string)
+			var err error
+			if other, ok := link["outwardIssue"].(map[string]interface{}); ok {
+				err = c.linkIssues(name, key, fmt.Sprintf("%v", other["key"]))
+			} else if other, ok := link["inwardIssue"].(map[string]interface{}); ok {
+				err = c.linkIssues(name, fmt.Sprintf("%v", other["key"]), key)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if withSubtasks {
+		subtasks, _ := fields["subtasks"].([]interface{})
+		for _, s := range subtasks {
+			subtask, ok := s.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			data, err := c.ViewIssue(fmt.Sprintf("%v", subtask["key"]))
+			if err != nil {
+				return nil, err
+			}
+			subtaskData, ok := data.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			subtaskFields, _, err := c.cloneIssueFields(subtaskData, project, issueField(subtaskData, "issuetype", "name"))
+			if err != nil {
+				return nil, err
+			}
+			subtaskFields["parent"] = map[string]interface{}{"key": key}
+			json, err := jsonEncode(map[string]interface{}{
+				"fields": subtaskFields,
+			})
+			if err != nil {
+				return nil, err
+			}
+			subtaskKey, err := c.createIssue(json)
+			if err != nil {
+				return nil, err
+			}
+			subtaskKeys[fmt.Sprintf("%v", subtask["key"])] = subtaskKey
+			if !c.GetOptBool("quiet", false) && subtaskKey != "" {
+				fmt.Printf("OK %s %s/browse/%s\n", subtaskKey, c.endpoint, subtaskKey)
+			}
+		}
+	}
+	return subtaskKeys, nil
+}
@@ -0,0 +1,208 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMapFields(t *testing.T) {
+	source := map[string]interface{}{
+		"project":     map[string]interface{}{"key": "GOJIRA"},
+		"summary":     "disk full",
+		"priority":    map[string]interface{}{"name": "High", "iconUrl": "http://example.com/high.png"},
+		"components":  []interface{}{map[string]interface{}{"id": "1", "name": "db"}, map[string]interface{}{"id": "2", "name": "web"}},
+		"environment": "prod",
+		"customfield_10100": []interface{}{
+			map[string]interface{}{"id": "3", "name": "Sprint 3"},
+		},
+		"timetracking": map[string]interface{}{"originalEstimate": "1h", "timeSpent": "2h"},
+		"labels":       []interface{}{},
+	}
+	meta := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"summary":  map[string]interface{}{},
+			"priority": map[string]interface{}{"allowedValues": []interface{}{map[string]interface{}{"id": "2", "name": "High"}}},
+			"components": map[string]interface{}{"allowedValues": []interface{}{
+				map[string]interface{}{"id": "1", "name": "db"},
+			}},
+			"customfield_10100": map[string]interface{}{"schema": map[string]interface{}{"custom": "com.pyxis.greenhopper.jira:gh-sprint"}},
+			"timetracking":      map[string]interface{}{},
+			"labels":            map[string]interface{}{},
+		},
+	}
+	fields, dropped := mapFields(source, meta)
+	expected := map[string]interface{}{
+		"summary":      "disk full",
+		"priority":     map[string]interface{}{"id": "2", "name": "High"},
+		"components":   []interface{}{map[string]interface{}{"id": "1", "name": "db"}},
+		"timetracking": map[string]interface{}{"originalEstimate": "1h"},
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected:\n%#v\ngot:\n%#v", expected, fields)
+	}
+	// the components that are not allowed are left out, environment is not a
+	// field of the target, and sprints can not be written back
+	droppedNames := map[string]bool{}
+	for _, name := range dropped {
+		droppedNames[name] = true
+	}
+	if !reflect.DeepEqual(droppedNames, map[string]bool{"environment": true, "customfield_10100": true}) {
+		t.Errorf("expected environment and customfield_10100 to be dropped, got %v", dropped)
+	}
+}
+
+func TestAddMissingFields(t *testing.T) {
+	issueJSON, err := addMissingFields(`{"fields":{"summary":"edited"}}`, map[string]interface{}{
+		"summary": "source",
+		"labels":  []interface{}{"db"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(issueJSON), &edited); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"summary": "edited",
+			"labels":  []interface{}{"db"},
+		},
+	}
+	if !reflect.DeepEqual(edited, expected) {
+		t.Errorf("expected:\n%#v\ngot:\n%#v", expected, edited)
+	}
+}
+
+// testCloneJira will return a fake jira with GOJIRA-1, a task with a subtask
+// GOJIRA-2 that blocks GOJIRA-5, the created issues are NEW-1, NEW-2 ...
+func testCloneJira(t *testing.T) (*testJira, map[string]interface{}) {
+	issue := func(key string, issuetype string, fields map[string]interface{}) map[string]interface{} {
+		fields["summary"] = key
+		fields["project"] = map[string]interface{}{"key": "GOJIRA"}
+		fields["issuetype"] = map[string]interface{}{"name": issuetype}
+		fields["labels"] = []interface{}{"db"}
+		return map[string]interface{}{"key": key, "fields": fields}
+	}
+	created := 0
+	server := newTestJira(t, map[string]testJiraHandler{
+		"GET /rest/api/2/issue/GOJIRA-1": testJiraRespond(200, issue("GOJIRA-1", "Task", map[string]interface{}{
+			"issuelinks": []interface{}{map[string]interface{}{
+				"type":         map[string]interface{}{"name": "Blocks"},
+				"outwardIssue": map[string]interface{}{"key": "GOJIRA-5"},
+			}},
+			"subtasks": []interface{}{map[string]interface{}{"key": "GOJIRA-2"}},
+		})),
+		"GET /rest/api/2/issue/GOJIRA-2": testJiraRespond(200, issue("GOJIRA-2", "Sub-task", map[string]interface{}{
+			"parent": map[string]interface{}{"key": "GOJIRA-1"},
+		})),
+		"GET /rest/api/2/issue/createmeta": testJiraRespond(200, map[string]interface{}{
+			"projects": []interface{}{map[string]interface{}{
+				"issuetypes": []interface{}{map[string]interface{}{
+					"fields": map[string]interface{}{
+						"project":   map[string]interface{}{"name": "Project", "schema": map[string]interface{}{"type": "project"}},
+						"issuetype": map[string]interface{}{"name": "Issue Type", "schema": map[string]interface{}{"type": "issuetype"}},
+						"summary":   map[string]interface{}{"name": "Summary", "schema": map[string]interface{}{"type": "string"}},
+						"labels":    map[string]interface{}{"name": "Labels", "schema": map[string]interface{}{"type": "array", "items": "string"}},
+					},
+				}},
+			}},
+		}),
+		"GET /rest/api/2/issueLinkType": testJiraRespond(200, map[string]interface{}{"issueLinkTypes": []interface{}{
+			map[string]interface{}{"name": "Cloners", "inward": "is cloned by", "outward": "clones"},
+			map[string]interface{}{"name": "Blocks", "inward": "is blocked by", "outward": "blocks"},
+		}}),
+		"POST /rest/api/2/issueLink": testJiraRespond(201, nil),
+		"POST /rest/api/2/issue": func(*http.Request, interface{}) (int, interface{}) {
+			created++
+			return 201, map[string]interface{}{"key": fmt.Sprintf("NEW-%d", created)}
+		},
+	})
+	// a plain create template, the clone adds the fields it does not show
+	template := filepath.Join(server.home, "create.yml")
+	if err := ioutil.WriteFile(template, []byte("fields:\n  project:\n    key: {{ .overrides.project }}\n  issuetype:\n    name: {{ .overrides.issuetype }}\n  summary: {{ .overrides.summary }}\n"), 0644); err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return server, map[string]interface{}{"template": template, "noedit": true, "edit": false, "quiet": true}
+}
+
+func TestCloneProject(t *testing.T) {
+	server, opts := testCloneJira(t)
+	defer server.Close()
+
+	// a configured project is the default for new issues, not for clones
+	opts["project"] = "OTHER"
+	c := server.cli(opts)
+	c.SetCommandLineOptions(map[string]interface{}{})
+	if err := c.CmdClone("GOJIRA-1"); err != nil {
+		t.Fatal(err)
+	}
+	// a project given on the command line is where the clone goes
+	c.SetCommandLineOptions(map[string]interface{}{"project": "new"})
+	if err := c.CmdClone("GOJIRA-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	created := server.sent("POST /rest/api/2/issue")
+	if len(created) != 2 {
+		t.Fatalf("expected 2 clones, got %d", len(created))
+	}
+	for i, project := range []string{"GOJIRA", "NEW"} {
+		fields := created[i].(map[string]interface{})["fields"].(map[string]interface{})
+		if key := fields["project"].(map[string]interface{})["key"]; key != project {
+			t.Errorf("expected clone %d in %s, got %v", i+1, project, key)
+		}
+		// labels are not in the template, they are copied from the source
+		if !reflect.DeepEqual(fields["labels"], []interface{}{"db"}) {
+			t.Errorf("expected the labels to be copied, got %v", fields["labels"])
+		}
+	}
+	// only the clone link, the source links and subtasks are left alone
+	if links := server.sent("POST /rest/api/2/issueLink"); len(links) != 2 {
+		t.Errorf("expected 2 clone links, got %d", len(links))
+	}
+}
+
+func TestCloneWithLinksAndSubtasks(t *testing.T) {
+	server, opts := testCloneJira(t)
+	defer server.Close()
+
+	opts["with-links"] = true
+	opts["with-subtasks"] = true
+	c := server.cli(opts)
+	if err := c.CmdClone("GOJIRA-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	created := server.sent("POST /rest/api/2/issue")
+	if len(created) != 2 {
+		t.Fatalf("expected the issue and its subtask to be cloned, got %d", len(created))
+	}
+	subtask := created[1].(map[string]interface{})["fields"].(map[string]interface{})
+	if parent := subtask["parent"].(map[string]interface{})["key"]; parent != "NEW-1" {
+		t.Errorf("expected the subtask under NEW-1, got %v", parent)
+	}
+	if issuetype := subtask["issuetype"].(map[string]interface{})["name"]; issuetype != "Sub-task" {
+		t.Errorf("expected a Sub-task, got %v", issuetype)
+	}
+
+	links := server.sent("POST /rest/api/2/issueLink")
+	if len(links) != 2 {
+		t.Fatalf("expected the clone link and the blocks link, got %d", len(links))
+	}
+	expected := []map[string]interface{}{
+		{"type": map[string]interface{}{"name": "Cloners"}, "inwardIssue": map[string]interface{}{"key": "NEW-1"}, "outwardIssue": map[string]interface{}{"key": "GOJIRA-1"}},
+		{"type": map[string]interface{}{"name": "Blocks"}, "inwardIssue": map[string]interface{}{"key": "NEW-1"}, "outwardIssue": map[string]interface{}{"key": "GOJIRA-5"}},
+	}
+	for i, link := range links {
+		if !reflect.DeepEqual(link, map[string]interface{}(expected[i])) {
+			t.Errorf("expected link %v, got %v", expected[i], link)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package jira
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/Netflix-Skunkworks/go-jira.v0/data"
+)
+
+const sprintCustomType = "com.pyxis.greenhopper.jira:gh-sprint"
+
+// coerceFields will convert the edited fields into the JSON structure jira
+// expects for each field, based on the field schema from the metadata.
+// Fields may be given by their display name (eg "Story Points") and are
+// renamed to their field id.  Any values that could not be converted are
+// returned as problems.
+func coerceFields(edited map[string]interface{}, metaFields jiradata.FieldMetaMap) []string {
+	problems := []string{}
+	fields, ok := edited["fields"].(map[string]interface{})
+	if !ok {
+		return problems
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		value := fields[key]
+		id := key
+		if _, ok := metaFields[key]; !ok {
+			for fieldID, fieldMeta := range metaFields {
+				if fieldMeta != nil && strings.EqualFold(fieldMeta.Name, key) {
+					id = fieldID
+					break
+				}
+			}
+		}
+		fieldMeta := metaFields[id]
+		if id != key {
+			delete(fields, key)
+		}
+		if fieldMeta == nil || fieldMeta.Schema == nil {
+			fields[id] = value
+			continue
+		}
+		coerced, err := coerceValue(value, fieldMeta.Schema)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("Invalid value %q for %s: %s", fmt.Sprintf("%v", value), fieldDisplayName(id, fieldMeta), err))
+			coerced = value
+		}
+		fields[id] = coerced
+	}
+	return problems
+}
+
+// coerceValue will convert a value for a field with the given schema, values
+// that are already structured (maps, or lists for array fields) are left
+// as is
+func coerceValue(value interface{}, schema *jiradata.JSONType) (interface{}, error) {
+	if _, ok := value.(map[string]interface{}); ok || isEmptyValue(value) {
+		return value, nil
+	}
+	if schema.Custom == sprintCustomType {
+		// sprints are set by id, not as a list
+		if list, ok := value.([]interface{}); ok && len(list) == 1 {
+			value = list[0]
+		}
+		return coerceScalar(value, "number")
+	}
+	if schema.Type == "array" {
+		var items []interface{}
+		switch v := value.(type) {
+		case []interface{}:
+			items = v
+		case string:
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		default:
+			items = []interface{}{v}
+		}
+		values := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			if _, ok := item.(map[string]interface{}); ok {
+				values = append(values, item)
+				continue
+			}
+			coerced, err := coerceScalar(item, schema.Items)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, coerced)
+		}
+		return values, nil
+	}
+	return coerceScalar(value, schema.Type)
+}
+
+// coerceScalar will convert a plain YAML value to the JSON structure used
+// for the schema type
+func coerceScalar(value interface{}, jsonType string) (interface{}, error) {
+	if jsonType == "string" {
+		if _, ok := value.(string); ok {
+			return value, nil
+		}
+		return fmt.Sprintf("%v", value), nil
+	}
+	str := strings.TrimSpace(fmt.Sprintf("%v", value))
+	if str == "" {
+		return value, nil
+	}
+	switch jsonType {
+	case "number":
+		if _, ok := value.(string); !ok {
+			return value, nil
+		}
+		n, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number")
+		}
+		return n, nil
+	case "date":
+		t, err := parseDate(str)
+		if err != nil {
+			return nil, err
+		}
+		return t.Format("2006-01-02"), nil
+	case "datetime":
+		t, err := parseDate(str)
+		if err != nil {
+			return nil, err
+		}
+		return t.Format(jiraTimeFormat), nil
+	case "user", "priority", "resolution", "version", "component", "securitylevel", "issuetype", "status":
+		return map[string]interface{}{"name": str}, nil
+	case "project", "issuelink":
+		return map[string]interface{}{"key": str}, nil
+	case "option":
+		return map[string]interface{}{"value": str}, nil
+	case "option-with-child":
+		parts := strings.SplitN(str, "/", 2)
+		option := map[string]interface{}{"value": strings.TrimSpace(parts[0])}
+		if len(parts) > 1 {
+			option["child"] = map[string]interface{}{"value": strings.TrimSpace(parts[1])}
+		}
+		return option, nil
+	}
+	return value, nil
+}
+
+// parseDate will parse the common ways of writing a date or time
+func parseDate(value string) (time.Time, error) {
+	for _, format := range []string{"2006-01-02", "2006/01/02", "2006-01-02 15:04", "2006-01-02T15:04:05", time.RFC3339, jiraTimeFormat} {
+		if t, err := time.ParseInLocation(format, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("expected a date like 2017-01-31 or 2017-01-31 13:30")
+}
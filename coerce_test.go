@@ -0,0 +1,115 @@
+package jira
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/Netflix-Skunkworks/go-jira.v0/data"
+)
+
+func testFieldMeta() jiradata.FieldMetaMap {
+	return jiradata.FieldMetaMap{
+		"summary":           {Name: "Summary", Schema: &jiradata.JSONType{Type: "string"}},
+		"customfield_10002": {Name: "Story Points", Schema: &jiradata.JSONType{Type: "number"}},
+		"labels":            {Name: "Labels", Schema: &jiradata.JSONType{Type: "array", Items: "string"}},
+		"components":        {Name: "Component/s", Schema: &jiradata.JSONType{Type: "array", Items: "component"}},
+		"priority":          {Name: "Priority", Schema: &jiradata.JSONType{Type: "priority"}},
+		"duedate":           {Name: "Due Date", Schema: &jiradata.JSONType{Type: "date"}},
+		"customfield_10003": {Name: "Sprint", Schema: &jiradata.JSONType{Type: "array", Items: "string", Custom: sprintCustomType}},
+		"customfield_10004": {Name: "Team", Schema: &jiradata.JSONType{Type: "option-with-child"}},
+	}
+}
+
+func TestCoerceFields(t *testing.T) {
+	edited := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"summary":      42,
+			"story points": "3.5",
+			"Labels":       "a, b,",
+			"components":   []interface{}{"ui", map[string]interface{}{"id": "10"}},
+			"priority":     "High",
+			"Due Date":     "2017/01/31",
+			"sprint":       []interface{}{"12"},
+			"team":         "Platform / Infra",
+			"unknown":      "left alone",
+		},
+	}
+	problems := coerceFields(edited, testFieldMeta())
+	if len(problems) > 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	expected := map[string]interface{}{
+		"summary":           "42",
+		"customfield_10002": 3.5,
+		"labels":            []interface{}{"a", "b"},
+		"components":        []interface{}{map[string]interface{}{"name": "ui"}, map[string]interface{}{"id": "10"}},
+		"priority":          map[string]interface{}{"name": "High"},
+		"duedate":           "2017-01-31",
+		"customfield_10003": 12.0,
+		"customfield_10004": map[string]interface{}{
+			"value": "Platform",
+			"child": map[string]interface{}{"value": "Infra"},
+		},
+		"unknown": "left alone",
+	}
+	fields := edited["fields"].(map[string]interface{})
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected:\n%#v\ngot:\n%#v", expected, fields)
+	}
+}
+
+func TestCoerceFieldsProblems(t *testing.T) {
+	edited := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"Story Points": "lots",
+			"duedate":      "tomorrow",
+		},
+	}
+	problems := coerceFields(edited, testFieldMeta())
+	if len(problems) != 2 {
+		t.Fatalf("expected 2 problems, got %v", problems)
+	}
+	// the invalid values are kept so they can be fixed in the editor
+	fields := edited["fields"].(map[string]interface{})
+	if fields["customfield_10002"] != "lots" || fields["duedate"] != "tomorrow" {
+		t.Errorf("expected the invalid values to be kept, got %#v", fields)
+	}
+}
+
+func TestCoerceValueStructured(t *testing.T) {
+	value := map[string]interface{}{"name": "High"}
+	coerced, err := coerceValue(value, &jiradata.JSONType{Type: "number"})
+	if err != nil || !reflect.DeepEqual(coerced, value) {
+		t.Errorf("expected structured values to be left as is, got %#v %v", coerced, err)
+	}
+	if coerced, err := coerceValue("", &jiradata.JSONType{Type: "number"}); err != nil || coerced != "" {
+		t.Errorf("expected empty values to be left as is, got %#v %v", coerced, err)
+	}
+}
+
+func TestCoerceValueDatetime(t *testing.T) {
+	coerced, err := coerceValue("2017-01-31 13:30", &jiradata.JSONType{Type: "datetime"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := time.Date(2017, 1, 31, 13, 30, 0, 0, time.Local).Format(jiraTimeFormat)
+	if coerced != expected {
+		t.Errorf("expected %s, got %v", expected, coerced)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	expected := time.Date(2017, 1, 31, 0, 0, 0, 0, time.Local)
+	for _, value := range []string{"2017-01-31", "2017/01/31", "2017-01-31 00:00", "2017-01-31T00:00:00"} {
+		date, err := parseDate(value)
+		if err != nil {
+			t.Errorf("unexpected error for %s: %s", value, err)
+		} else if !date.Equal(expected) {
+			t.Errorf("expected %s for %s, got %s", expected, value, date)
+		}
+	}
+	if _, err := parseDate("31/01/2017"); err == nil {
+		t.Error("expected an error for 31/01/2017")
+	}
+}
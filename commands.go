@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"gopkg.in/Netflix-Skunkworks/go-jira.v0/data"
 	// "github.com/kr/pretty"
@@ -93,6 +96,9 @@ func (c *Cli) CmdFields() error {
 // CmdList will query jira and send data to "list" template
 func (c *Cli) CmdList() error {
 	log.Debugf("list called")
+	if c.getOptString("output", "") != "" {
+		return c.outputIssues()
+	}
 	data, err := c.FindIssues()
 	if err != nil {
 		return err
@@ -108,69 +114,638 @@ func (c *Cli) CmdView(issue string) error {
 	if err != nil {
 		return err
 	}
-	return runTemplate(c.getTemplate("view"), data, nil)
+	// remote links are not part of the issue fields, so they are only
+	// fetched with the "remotelinks" expansion
+	if issueData, ok := data.(map[string]interface{}); ok && c.expanded("remotelinks") && !c.getOptBool("offline", false) {
+		// dont fail the view if we cant get them
+		if remoteLinks, err := c.RemoteLinks(issue); err == nil {
+			issueData["remotelinks"] = remoteLinks
+		} else {
+			log.Warningf("Failed to get remote links for %s: %s", issue, err)
+		}
+	}
+	if c.getOptString("output", "") != "" {
+		return c.outputIssue(data)
+	}
+	return runTemplate(c.getTemplate("view"), data, nil)
+}
+
+// RemoteLinks will return the remote (web) links for the given issue
+func (c *Cli) RemoteLinks(issue string) ([]interface{}, error) {
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s/remotelink", c.endpoint, issue)
+	resp, err := c.get(uri)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Unexpected Response From GET: %s", resp.Status)
+	}
+	data, err := responseToJSON(resp, nil)
+	if err != nil {
+		return nil, err
+	}
+	if links, ok := data.([]interface{}); ok {
+		return links, nil
+	}
+	return nil, fmt.Errorf("Unexpected remote links response for %s", issue)
+}
+
+// CmdRemoteLinks will send the remote links for the given issue to the "remotelinks" template
+func (c *Cli) CmdRemoteLinks(issue string) error {
+	log.Debugf("remotelinks called")
+	c.Browse(issue)
+	links, err := c.RemoteLinks(issue)
+	if err != nil {
+		log.Errorf("%s", err)
+		return err
+	}
+	return runTemplate(c.getTemplate("remotelinks"), links, nil)
+}
+
+// CmdRemoteLinkAdd will add a remote link to the given url on the issue.  The
+// url is used as the globalId of the link, so adding the same url again will
+// update the existing link (with a new 'title' or 'icon') rather than add
+// a duplicate.
+func (c *Cli) CmdRemoteLinkAdd(issue string, link string) error {
+	log.Debugf("remotelink add called")
+
+	object := map[string]interface{}{
+		"url":   link,
+		"title": c.GetOptString("title", link),
+	}
+	if icon := c.GetOptString("icon", ""); icon != "" {
+		object["icon"] = map[string]interface{}{
+			"url16x16": icon,
+			"title":    c.GetOptString("title", link),
+		}
+	}
+	json, err := jsonEncode(map[string]interface{}{
+		"globalId": link,
+		"object":   object,
+	})
+	if err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s/remotelink", c.endpoint, issue)
+	if c.getOptBool("dryrun", false) {
+		log.Debugf("POST: %s", json)
+		log.Debugf("Dryrun mode, skipping POST")
+		return nil
+	}
+	resp, err := c.post(uri, json)
+	if err != nil {
+		return err
+	}
+	// 201 for a new link, 200 when the globalId matched an existing link
+	if resp.StatusCode == 201 || resp.StatusCode == 200 {
+		c.Browse(issue)
+		if !c.GetOptBool("quiet", false) {
+			fmt.Printf("OK %s %s/browse/%s\n", issue, c.endpoint, issue)
+		}
+		return nil
+	}
+	logBuffer := bytes.NewBuffer(make([]byte, 0))
+	resp.Write(logBuffer)
+	err = fmt.Errorf("Unexpected Response From POST")
+	log.Errorf("%s:\n%s", err, logBuffer)
+	return err
+}
+
+// CmdRemoteLinkRemove will delete the given remote link id from the issue
+func (c *Cli) CmdRemoteLinkRemove(issue string, id string) error {
+	log.Debugf("remotelink rm called")
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s/remotelink/%s", c.endpoint, issue, id)
+	if c.getOptBool("dryrun", false) {
+		log.Debugf("DELETE: %s", uri)
+		log.Debugf("Dryrun mode, skipping DELETE")
+		return nil
+	}
+	resp, err := c.delete(uri)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == 204 {
+		c.Browse(issue)
+		if !c.GetOptBool("quiet", false) {
+			fmt.Printf("OK %s %s/browse/%s\n", issue, c.endpoint, issue)
+		}
+		return nil
+	}
+	logBuffer := bytes.NewBuffer(make([]byte, 0))
+	resp.Write(logBuffer)
+	err = fmt.Errorf("Unexpected Response From DELETE")
+	log.Errorf("%s:\n%s", err, logBuffer)
+	return err
+}
+
+// CmdHistory will get the changelog for the given issue and send it to the
+// "history" template.  The changelog entries can be restricted with the
+// 'field', 'author' and 'since' options.
+func (c *Cli) CmdHistory(issue string) error {
+	log.Debugf("history called")
+	c.Browse(issue)
+
+	var since time.Time
+	if val := c.getOptString("since", ""); val != "" {
+		var err error
+		if since, err = parseSince(val); err != nil {
+			return err
+		}
+	}
+
+	data, err := c.viewIssue(issue, "changelog")
+	if err != nil {
+		return err
+	}
+	issueData, ok := data.(map[string]interface{})
+	if !ok {
+		err := fmt.Errorf("Unexpected response for issue %s", issue)
+		log.Errorf("%s", err)
+		return err
+	}
+	if changelog, ok := issueData["changelog"].(map[string]interface{}); ok {
+		if histories, ok := changelog["histories"].([]interface{}); ok {
+			changelog["histories"] = filterHistories(
+				histories,
+				c.getOptStrings("field"),
+				c.getOptString("author", ""),
+				since,
+			)
+		}
+	}
+	return runTemplate(c.getTemplate("history"), issueData, nil)
+}
+
+// longTextFields are changelog fields that are displayed as a unified diff
+// rather than as from/to values
+var longTextFields = map[string]bool{
+	"description": true,
+	"environment": true,
+}
+
+func filterHistories(histories []interface{}, fields []string, author string, since time.Time) []interface{} {
+	filtered := make([]interface{}, 0, len(histories))
+	for _, h := range histories {
+		history, ok := h.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if author != "" {
+			who, _ := history["author"].(map[string]interface{})
+			if !strings.EqualFold(fmt.Sprintf("%v", who["name"]), author) && !strings.EqualFold(fmt.Sprintf("%v", who["displayName"]), author) {
+				continue
+			}
+		}
+		if !since.IsZero() {
+			created, err := time.Parse(jiraTimeFormat, fmt.Sprintf("%v", history["created"]))
+			if err != nil || created.Before(since) {
+				continue
+			}
+		}
+
+		items, _ := history["items"].([]interface{})
+		keep := make([]interface{}, 0, len(items))
+		for _, i := range items {
+			item, ok := i.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			field, _ := item["field"].(string)
+			if len(fields) > 0 {
+				found := false
+				for _, f := range fields {
+					if strings.EqualFold(strings.TrimSpace(f), field) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					continue
+				}
+			}
+			from, _ := item["fromString"].(string)
+			to, _ := item["toString"].(string)
+			if longTextFields[strings.ToLower(field)] || strings.Contains(from, "\n") || strings.Contains(to, "\n") {
+				item["diff"] = unifiedDiff(from, to)
+			}
+			keep = append(keep, item)
+		}
+		if len(keep) == 0 {
+			continue
+		}
+		history["items"] = keep
+		filtered = append(filtered, history)
+	}
+	return filtered
+}
+
+// CmdWorklogs will get worklog data for given issue and sent to the "worklogs" template
+func (c *Cli) CmdWorklogs(issue string) error {
+	log.Debugf("worklogs called")
+	c.Browse(issue)
+	data, err := c.ViewIssueWorkLogs(issue)
+	if err != nil {
+		return err
+	}
+	return runTemplate(c.getTemplate("worklogs"), data, nil)
+}
+
+// CmdWorklog will attempt to add (action=add) a worklog to the given issue.
+// It will spawn the editor (unless --noedit isused) and post edited YAML
+// content as JSON to the worklog endpoint
+func (c *Cli) CmdWorklog(action string, issue string) error {
+	log.Debugf("%s worklog called", action)
+	if action != "add" {
+		err := fmt.Errorf("CmdWorklog: %q is not a valid action", action)
+		log.Errorf("%s", err)
+		return err
+	}
+	c.Browse(issue)
+
+	params, err := c.worklogEstimateParams("add")
+	if err != nil {
+		return err
+	}
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s/worklog%s", c.endpoint, issue, params)
+
+	worklogData := map[string]interface{}{
+		"issue":   issue,
+		"comment": c.opts["comment"],
+		"started": time.Now().Format(jiraTimeFormat),
+	}
+
+	if v, ok := c.opts["time-spent"].(string); ok {
+		if err := validateDuration("time-spent", v); err != nil {
+			return err
+		}
+		worklogData["timeSpent"] = v
+	}
+
+	return c.editTemplate(
+		c.getTemplate("worklog"),
+		fmt.Sprintf("%s-worklog-", issue),
+		worklogData,
+		func(json string) error {
+			if err := validateWorklog(json); err != nil {
+				return err
+			}
+			if c.getOptBool("dryrun", false) {
+				log.Debugf("POST: %s", json)
+				log.Debugf("Dryrun mode, skipping POST")
+				return nil
+			}
+			resp, err := c.post(uri, json)
+			if err != nil {
+				return err
+			}
+
+			if resp.StatusCode == 201 {
+				c.Browse(issue)
+				if !c.GetOptBool("quiet", false) {
+					fmt.Printf("OK %s %s/browse/%s\n", issue, c.endpoint, issue)
+				}
+				return nil
+			}
+			logBuffer := bytes.NewBuffer(make([]byte, 0))
+			resp.Write(logBuffer)
+			err = fmt.Errorf("Unexpected Response From POST")
+			log.Errorf("%s:\n%s", err, logBuffer)
+			return err
+		},
+	)
+}
+
+// CmdWorklogEdit will open the existing worklog in the "worklog" template
+// for editing then submit the YAML output to jira
+func (c *Cli) CmdWorklogEdit(issue string, id string) error {
+	log.Debugf("worklog edit called")
+	params, err := c.worklogEstimateParams("edit")
+	if err != nil {
+		return err
+	}
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s/worklog/%s", c.endpoint, issue, id)
+	data, err := responseToJSON(c.get(uri))
+	if err != nil {
+		return err
+	}
+	worklog, ok := data.(map[string]interface{})
+	if !ok || worklog["id"] == nil {
+		err := fmt.Errorf("Worklog %s not found on %s", id, issue)
+		log.Errorf("%s", err)
+		return err
+	}
+
+	worklogData := map[string]interface{}{
+		"issue":     issue,
+		"comment":   worklog["comment"],
+		"timeSpent": worklog["timeSpent"],
+		"started":   worklog["started"],
+	}
+	if v, ok := c.opts["comment"].(string); ok && v != "" {
+		worklogData["comment"] = v
+	}
+	if v, ok := c.opts["time-spent"].(string); ok {
+		if err := validateDuration("time-spent", v); err != nil {
+			return err
+		}
+		worklogData["timeSpent"] = v
+	}
+
+	return c.editTemplate(
+		c.getTemplate("worklog"),
+		fmt.Sprintf("%s-worklog-%s-", issue, id),
+		worklogData,
+		func(json string) error {
+			if err := validateWorklog(json); err != nil {
+				return err
+			}
+			if c.getOptBool("dryrun", false) {
+				log.Debugf("PUT: %s", json)
+				log.Debugf("Dryrun mode, skipping PUT")
+				return nil
+			}
+			resp, err := c.put(uri+params, json)
+			if err != nil {
+				return err
+			}
+
+			if resp.StatusCode == 200 {
+				c.Browse(issue)
+				if !c.GetOptBool("quiet", false) {
+					fmt.Printf("OK %s %s/browse/%s\n", issue, c.endpoint, issue)
+				}
+				return nil
+			}
+			logBuffer := bytes.NewBuffer(make([]byte, 0))
+			resp.Write(logBuffer)
+			err = fmt.Errorf("Unexpected Response From PUT")
+			log.Errorf("%s:\n%s", err, logBuffer)
+			return err
+		},
+	)
+}
+
+// CmdWorklogRemove will delete the given worklog id from the issue
+func (c *Cli) CmdWorklogRemove(issue string, id string) error {
+	log.Debugf("worklog rm called")
+	params, err := c.worklogEstimateParams("rm")
+	if err != nil {
+		return err
+	}
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s/worklog/%s%s", c.endpoint, issue, id, params)
+	if c.getOptBool("dryrun", false) {
+		log.Debugf("DELETE: %s", uri)
+		log.Debugf("Dryrun mode, skipping DELETE")
+		return nil
+	}
+	resp, err := c.delete(uri)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == 204 {
+		c.Browse(issue)
+		if !c.GetOptBool("quiet", false) {
+			fmt.Printf("OK %s %s/browse/%s\n", issue, c.endpoint, issue)
+		}
+		return nil
+	}
+	logBuffer := bytes.NewBuffer(make([]byte, 0))
+	resp.Write(logBuffer)
+	err = fmt.Errorf("Unexpected Response From DELETE")
+	log.Errorf("%s:\n%s", err, logBuffer)
+	return err
+}
+
+// worklogEstimateParams will build the query string for the 'adjust-estimate'
+// option.  The modes are:
+//
+//	new:    set the remaining estimate to 'new-estimate'
+//	leave:  leave the remaining estimate as is
+//	manual: reduce the remaining estimate by 'reduce-by', or when removing a
+//	        worklog, increase it by 'increase-by'
+//	auto:   let jira adjust the remaining estimate (the default)
+//
+// The action is the worklog action ("add", "edit" or "rm"), jira does not
+// accept manual when a worklog is updated.
+func (c *Cli) worklogEstimateParams(action string) (string, error) {
+	mode := c.getOptString("adjust-estimate", "")
+	if mode == "" {
+		return "", nil
+	}
+	params := url.Values{}
+	params.Set("adjustEstimate", mode)
+	switch mode {
+	case "auto", "leave":
+	case "new":
+		estimate := c.getOptString("new-estimate", "")
+		if estimate == "" {
+			err := fmt.Errorf("adjust-estimate=new requires the new-estimate option")
+			log.Errorf("%s", err)
+			return "", err
+		}
+		if err := validateDuration("new-estimate", estimate); err != nil {
+			return "", err
+		}
+		params.Set("newEstimate", estimate)
+	case "manual":
+		if action == "edit" {
+			err := fmt.Errorf("adjust-estimate=manual is not supported when editing a worklog, use one of: new, leave, auto")
+			log.Errorf("%s", err)
+			return "", err
+		}
+		name, param := "reduce-by", "reduceBy"
+		if action == "rm" {
+			name, param = "increase-by", "increaseBy"
+		}
+		amount := c.getOptString(name, "")
+		if amount == "" {
+			err := fmt.Errorf("adjust-estimate=manual requires the %s option", name)
+			log.Errorf("%s", err)
+			return "", err
+		}
+		if err := validateDuration(name, amount); err != nil {
+			return "", err
+		}
+		params.Set(param, amount)
+	default:
+		err := fmt.Errorf("Invalid adjust-estimate %q, must be one of: new, leave, manual, auto", mode)
+		log.Errorf("%s", err)
+		return "", err
+	}
+	return "?" + params.Encode(), nil
+}
+
+// validateWorklog will verify the timeSpent in the edited worklog document
+// so we can report a useful error rather than a generic 400 from jira
+func validateWorklog(content string) error {
+	worklog := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(content), &worklog); err != nil {
+		return err
+	}
+	timeSpent, ok := worklog["timeSpent"]
+	if !ok {
+		err := fmt.Errorf("timeSpent is required for a worklog")
+		log.Errorf("%s", err)
+		return err
+	}
+	return validateDuration("timeSpent", fmt.Sprintf("%v", timeSpent))
+}
+
+// CmdAttach will upload the given files as attachments on the issue
+func (c *Cli) CmdAttach(issue string, files []string) error {
+	log.Debugf("attach called")
+	if err := c.attachFiles(issue, files); err != nil {
+		return err
+	}
+	c.Browse(issue)
+	if !c.GetOptBool("quiet", false) {
+		fmt.Printf("OK %s %s/browse/%s\n", issue, c.endpoint, issue)
+	}
+	return nil
+}
+
+// checkAttachFiles will return an error if any of the files can not be
+// attached, so commands can check before they create anything
+func checkAttachFiles(files []string) error {
+	for _, file := range files {
+		if stat, err := os.Stat(file); err != nil {
+			log.Errorf("Failed to stat %s: %s", file, err)
+			return err
+		} else if stat.IsDir() {
+			err := fmt.Errorf("%s is a directory, unable to attach", file)
+			log.Errorf("%s", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cli) attachFiles(issue string, files []string) error {
+	if len(files) == 0 {
+		return nil
+	}
+	if err := checkAttachFiles(files); err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s/attachments", c.endpoint, issue)
+	if c.getOptBool("dryrun", false) {
+		log.Debugf("POST: %s", strings.Join(files, ", "))
+		log.Debugf("Dryrun mode, skipping POST")
+		return nil
+	}
+	resp, err := c.upload(uri, files)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 200 {
+		logBuffer := bytes.NewBuffer(make([]byte, 0))
+		resp.Write(logBuffer)
+		err := fmt.Errorf("Unexpected Response From POST")
+		log.Errorf("%s:\n%s", err, logBuffer)
+		return err
+	}
+	return nil
+}
+
+// CmdAttachments will send the attachment details for the given issue to the "attachments" template
+func (c *Cli) CmdAttachments(issue string) error {
+	log.Debugf("attachments called")
+	c.Browse(issue)
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s?fields=attachment", c.endpoint, issue)
+	data, err := responseToJSON(c.get(uri))
+	if err != nil {
+		return err
+	}
+	return runTemplate(c.getTemplate("attachments"), data, nil)
+}
+
+// CmdAttachmentGet will download the content of the given attachment id.  The
+// content is written to the 'outfile' option if provided ("-" for stdout),
+// otherwise to the attachment filename in the current directory.
+func (c *Cli) CmdAttachmentGet(id string) error {
+	log.Debugf("attachment get called")
+	uri := fmt.Sprintf("%s/rest/api/2/attachment/%s", c.endpoint, id)
+	data, err := responseToJSON(c.get(uri))
+	if err != nil {
+		return err
+	}
+	attachment, ok := data.(map[string]interface{})
+	if !ok {
+		err := fmt.Errorf("Unexpected response for attachment %s", id)
+		log.Errorf("%s", err)
+		return err
+	}
+	content, _ := attachment["content"].(string)
+	if content == "" {
+		err := fmt.Errorf("Attachment %s not found", id)
+		log.Errorf("%s", err)
+		return err
+	}
+
+	outfile := c.getOptString("outfile", "")
+	if outfile == "" {
+		filename, _ := attachment["filename"].(string)
+		// never trust the server to give us a path
+		outfile = filepath.Base(filename)
+	}
+
+	resp, err := c.download(content)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 200 {
+		err := fmt.Errorf("Unexpected Response From GET: %s", resp.Status)
+		log.Errorf("%s", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	var out io.Writer = os.Stdout
+	if outfile != "-" {
+		fh, err := os.OpenFile(outfile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			log.Errorf("Failed to open %s for writing: %s", outfile, err)
+			return err
+		}
+		defer fh.Close()
+		out = fh
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		log.Errorf("Failed to write %s: %s", outfile, err)
+		return err
+	}
+	if outfile != "-" && !c.GetOptBool("quiet", false) {
+		fmt.Printf("OK %s %s\n", id, outfile)
+	}
+	return nil
 }
 
-// CmdWorklogs will get worklog data for given issue and sent to the "worklogs" template
-func (c *Cli) CmdWorklogs(issue string) error {
-	log.Debugf("worklogs called")
-	c.Browse(issue)
-	data, err := c.ViewIssueWorkLogs(issue)
+// CmdAttachmentRemove will delete the given attachment id
+func (c *Cli) CmdAttachmentRemove(id string) error {
+	log.Debugf("attachment rm called")
+	uri := fmt.Sprintf("%s/rest/api/2/attachment/%s", c.endpoint, id)
+	if c.getOptBool("dryrun", false) {
+		log.Debugf("DELETE: %s", uri)
+		log.Debugf("Dryrun mode, skipping DELETE")
+		return nil
+	}
+	resp, err := c.delete(uri)
 	if err != nil {
 		return err
 	}
-	return runTemplate(c.getTemplate("worklogs"), data, nil)
-}
-
-// CmdWorklog will attempt to add (action=add) a worklog to the given issue.
-// It will spawn the editor (unless --noedit isused) and post edited YAML
-// content as JSON to the worklog endpoint
-func (c *Cli) CmdWorklog(action string, issue string) error {
-	log.Debugf("%s worklog called", action)
-	c.Browse(issue)
-	if action == "add" {
-		uri := fmt.Sprintf("%s/rest/api/2/issue/%s/worklog", c.endpoint, issue)
-
-		worklogData := map[string]interface{}{
-			"issue":   issue,
-			"comment": c.opts["comment"],
-		}
-
-		if v, ok := c.opts["time-spent"].(string); ok {
-			worklogData["timeSpent"] = v
+	if resp.StatusCode == 204 {
+		if !c.GetOptBool("quiet", false) {
+			fmt.Printf("OK %s\n", id)
 		}
-
-		return c.editTemplate(
-			c.getTemplate("worklog"),
-			fmt.Sprintf("%s-worklog-", issue),
-			worklogData,
-			func(json string) error {
-				if c.getOptBool("dryrun", false) {
-					log.Debugf("POST: %s", json)
-					log.Debugf("Dryrun mode, skipping POST")
-					return nil
-				}
-				resp, err := c.post(uri, json)
-				if err != nil {
-					return err
-				}
-
-				if resp.StatusCode == 201 {
-					c.Browse(issue)
-					if !c.GetOptBool("quiet", false) {
-						fmt.Printf("OK %s %s/browse/%s\n", issue, c.endpoint, issue)
-					}
-					return nil
-				}
-				logBuffer := bytes.NewBuffer(make([]byte, 0))
-				resp.Write(logBuffer)
-				err = fmt.Errorf("Unexpected Response From POST")
-				log.Errorf("%s:\n%s", err, logBuffer)
-				return err
-			},
-		)
+		return nil
 	}
-	return nil
+	logBuffer := bytes.NewBuffer(make([]byte, 0))
+	resp.Write(logBuffer)
+	err = fmt.Errorf("Unexpected Response From DELETE")
+	log.Errorf("%s:\n%s", err, logBuffer)
+	return err
 }
 
 // CmdEdit will populate "edit" template with issue data and issue "editmeta" data.
@@ -370,8 +945,15 @@ func (c *Cli) CmdTransitions(issue string) error {
 // will parse the edited document as YAML and submit the document to jira.
 func (c *Cli) CmdCreate() error {
 	log.Debugf("create called")
-	project := c.opts["project"].(string)
-	issuetype := c.getOptString("issuetype", "")
+	// the project and issuetype of a prepared document take precedence
+	project := c.fileDocumentField("project", "key")
+	if project == "" {
+		project = c.getOptString("project", "")
+	}
+	issuetype := c.fileDocumentField("issuetype", "name")
+	if issuetype == "" {
+		issuetype = c.getOptString("issuetype", "")
+	}
 	if issuetype == "" {
 		issuetype = c.defaultIssueType()
 	}
@@ -385,49 +967,83 @@ func (c *Cli) CmdCreate() error {
 		return err
 	}
 	issueData["meta"] = meta
+	issueData["defaults"] = map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":   map[string]interface{}{"key": project},
+			"issuetype": map[string]interface{}{"name": issuetype},
+		},
+	}
 
 	sanitizedType := strings.ToLower(strings.Replace(issuetype, " ", "", -1))
-	return c.editTemplate(
+	attachments := c.getOptStrings("attach")
+	if err := checkAttachFiles(attachments); err != nil {
+		return err
+	}
+	// the attachments are uploaded once the template is done with, so a
+	// failed upload never offers to edit and create the issue again
+	var key string
+	err = c.editTemplate(
 		c.getTemplate(fmt.Sprintf("create-%s", sanitizedType)),
 		fmt.Sprintf("create-%s-", sanitizedType),
 		issueData,
 		func(json string) error {
-			uri := fmt.Sprintf("%s/rest/api/2/issue", c.endpoint)
-			if c.getOptBool("dryrun", false) {
-				log.Debugf("POST: %s", json)
-				log.Debugf("Dryrun mode, skipping POST")
-				return nil
-			}
-			resp, err := c.post(uri, json)
-			if err != nil {
-				return err
-			}
-
-			if resp.StatusCode == 201 {
-				// response: {"id":"410836","key":"PROJ-238","self":"https://jira/rest/api/2/issue/410836"}
-				json, err := responseToJSON(resp, nil)
-				if err != nil {
-					return err
-				}
-				key := json.(map[string]interface{})["key"].(string)
-				link := fmt.Sprintf("%s/browse/%s", c.endpoint, key)
-				c.Browse(key)
-				c.SaveData(map[string]string{
-					"issue": key,
-					"link":  link,
-				})
-				if !c.GetOptBool("quiet", false) {
-					fmt.Printf("OK %s %s\n", key, link)
-				}
-				return nil
-			}
-			logBuffer := bytes.NewBuffer(make([]byte, 0))
-			resp.Write(logBuffer)
-			err = fmt.Errorf("Unexpected Response From POST")
-			log.Errorf("%s:\n%s", err, logBuffer)
+			created, err := c.createIssue(json)
+			key = created
 			return err
 		},
 	)
+	if err != nil || key == "" {
+		return err
+	}
+	link := fmt.Sprintf("%s/browse/%s", c.endpoint, key)
+	c.SaveData(map[string]string{
+		"issue": key,
+		"link":  link,
+	})
+	if err := c.attachFiles(key, attachments); err != nil {
+		return err
+	}
+	c.Browse(key)
+	if !c.GetOptBool("quiet", false) {
+		fmt.Printf("OK %s %s\n", key, link)
+	}
+	return nil
+}
+
+// createIssue will POST the issue json document to jira and return the new
+// issue key.  In dryrun mode the key will be empty.
+func (c *Cli) createIssue(json string) (string, error) {
+	uri := fmt.Sprintf("%s/rest/api/2/issue", c.endpoint)
+	if c.getOptBool("dryrun", false) {
+		log.Debugf("POST: %s", json)
+		log.Debugf("Dryrun mode, skipping POST")
+		return "", nil
+	}
+	resp, err := c.post(uri, json)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode == 201 {
+		// response: {"id":"410836","key":"PROJ-238","self":"https://jira/rest/api/2/issue/410836"}
+		data, err := responseToJSON(resp, nil)
+		if err != nil {
+			return "", err
+		}
+		if created, ok := data.(map[string]interface{}); ok {
+			if key, ok := created["key"].(string); ok {
+				return key, nil
+			}
+		}
+		err = fmt.Errorf("Missing issue key in create response")
+		log.Errorf("%s", err)
+		return "", err
+	}
+	logBuffer := bytes.NewBuffer(make([]byte, 0))
+	resp.Write(logBuffer)
+	err = fmt.Errorf("Unexpected Response From POST")
+	log.Errorf("%s:\n%s", err, logBuffer)
+	return "", err
 }
 
 func (c *Cli) createIssueMetaData(project, issuetype string) (interface{}, error) {
@@ -476,48 +1092,162 @@ func (c *Cli) CmdSubtask(issue string) error {
 		return err
 	}
 	subtaskData["meta"] = meta
+	subtaskData["defaults"] = map[string]interface{}{
+		"fields": map[string]interface{}{
+			"parent":    map[string]interface{}{"key": parentData.(map[string]interface{})["key"]},
+			"project":   map[string]interface{}{"key": project},
+			"issuetype": map[string]interface{}{"name": "Sub-task"},
+		},
+	}
 
-	return c.editTemplate(
+	attachments := c.getOptStrings("attach")
+	if err := checkAttachFiles(attachments); err != nil {
+		return err
+	}
+	// the attachments are uploaded once the template is done with, so a
+	// failed upload never offers to edit and create the issue again
+	var key string
+	err = c.editTemplate(
 		c.getTemplate("subtask"),
 		"subtask-",
 		subtaskData,
 		func(json string) error {
-			uri := fmt.Sprintf("%s/rest/api/2/issue", c.endpoint)
-			if c.getOptBool("dryrun", false) {
-				log.Debugf("POST: %s", json)
-				log.Debugf("Dryrun mode, skipping POST")
-				return nil
-			}
-			resp, err := c.post(uri, json)
-			if err != nil {
-				return err
-			}
+			created, err := c.createIssue(json)
+			key = created
+			return err
+		},
+	)
+	if err != nil || key == "" {
+		return err
+	}
+	link := fmt.Sprintf("%s/browse/%s", c.endpoint, key)
+	c.SaveData(map[string]string{
+		"issue": key,
+		"link":  link,
+	})
+	if err := c.attachFiles(key, attachments); err != nil {
+		return err
+	}
+	c.Browse(key)
+	if !c.GetOptBool("quiet", false) {
+		fmt.Printf("OK %s %s\n", key, link)
+	}
+	return nil
+}
 
-			if resp.StatusCode == 201 {
-				// response: {"id":"410836","key":"PROJ-238","self":"https://jira/rest/api/2/issue/410836"}
-				json, err := responseToJSON(resp, nil)
-				if err != nil {
-					return err
-				}
-				key := json.(map[string]interface{})["key"].(string)
-				link := fmt.Sprintf("%s/browse/%s", c.endpoint, key)
-				c.Browse(key)
-				c.SaveData(map[string]string{
-					"issue": key,
-					"link":  link,
-				})
-				if !c.GetOptBool("quiet", false) {
-					fmt.Printf("OK %s %s\n", key, link)
-				}
-				return nil
+// CmdDelete will delete the given issues.  The issues and their summaries are
+// listed and the user is asked to confirm the delete unless the 'yes' option
+// is set.  Jira will refuse to delete an issue with subtasks unless the
+// 'subtasks' option is set, in which case the subtasks are deleted as well.
+func (c *Cli) CmdDelete(issues ...string) error {
+	log.Debugf("delete called")
+	found := []interface{}{}
+	for _, issue := range issues {
+		data, err := c.ViewIssue(issue)
+		if err != nil {
+			return err
+		}
+		if issueData, ok := data.(map[string]interface{}); ok && issueData["key"] != nil {
+			found = append(found, issueData)
+		} else {
+			err := fmt.Errorf("Issue %s not found", issue)
+			log.Errorf("%s", err)
+			return err
+		}
+	}
+	return c.deleteIssues(found)
+}
+
+// CmdDeleteQuery will delete every issue matching the 'query' option, after
+// showing how many issues matched and asking the user to confirm.  The query
+// must be given on the command line, a query from the config files is never
+// enough to delete issues.
+func (c *Cli) CmdDeleteQuery() error {
+	log.Debugf("delete query called")
+	query := c.getCmdlineOptString("query", "")
+	if query == "" {
+		err := fmt.Errorf("Missing required arguments, either ISSUE or -q JQL are required")
+		log.Errorf("%s", err)
+		return err
+	}
+	c.opts["query"] = query
+	// we only need enough to show what is about to be deleted
+	c.opts["queryfields"] = "summary,subtasks"
+	data, err := c.FindIssues()
+	if err != nil {
+		return err
+	}
+	results, _ := data.(map[string]interface{})
+	issues, _ := results["issues"].([]interface{})
+	if len(issues) == 0 {
+		log.Warningf("No issues found for query: %s", c.opts["query"])
+		return nil
+	}
+	if total, ok := results["total"].(float64); ok && int(total) > len(issues) {
+		fmt.Printf("Query matched %d issues, only the first %d will be deleted\n", int(total), len(issues))
+	} else {
+		fmt.Printf("Query matched %d issues\n", len(issues))
+	}
+	return c.deleteIssues(issues)
+}
+
+func (c *Cli) deleteIssues(issues []interface{}) error {
+	withSubtasks := c.getOptBool("subtasks", false)
+	keys := make([]string, 0, len(issues))
+	for _, i := range issues {
+		issue, _ := i.(map[string]interface{})
+		key, _ := issue["key"].(string)
+		fields, _ := issue["fields"].(map[string]interface{})
+		subtasks, _ := fields["subtasks"].([]interface{})
+		if !c.getOptBool("yes", false) {
+			if len(subtasks) > 0 && withSubtasks {
+				fmt.Printf("%s: %s (and %d subtasks)\n", key, fields["summary"], len(subtasks))
+			} else {
+				fmt.Printf("%s: %s\n", key, fields["summary"])
 			}
+		}
+		if len(subtasks) > 0 && !withSubtasks {
+			err := fmt.Errorf("Issue %s has %d subtasks, use --subtasks to delete them as well", key, len(subtasks))
+			log.Errorf("%s", err)
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	if !c.getOptBool("yes", false) {
+		if !promptYN(fmt.Sprintf("Delete %d issues?", len(keys)), false) {
+			err := fmt.Errorf("Delete cancelled")
+			log.Errorf("%s", err)
+			return err
+		}
+	}
+
+	for _, key := range keys {
+		uri := fmt.Sprintf("%s/rest/api/2/issue/%s", c.endpoint, key)
+		if withSubtasks {
+			uri += "?deleteSubtasks=true"
+		}
+		if c.getOptBool("dryrun", false) {
+			log.Debugf("DELETE: %s", uri)
+			log.Debugf("Dryrun mode, skipping DELETE")
+			continue
+		}
+		resp, err := c.delete(uri)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != 204 {
 			logBuffer := bytes.NewBuffer(make([]byte, 0))
 			resp.Write(logBuffer)
-			err = fmt.Errorf("Unexpected Response From POST")
+			err = fmt.Errorf("Unexpected Response From DELETE")
 			log.Errorf("%s:\n%s", err, logBuffer)
 			return err
-		},
-	)
+		}
+		if !c.GetOptBool("quiet", false) {
+			fmt.Printf("OK %s\n", key)
+		}
+	}
+	return nil
 }
 
 // CmdIssueLinkTypes will send the issue link type data to the "issuelinktypes" template.
@@ -528,13 +1258,229 @@ func (c *Cli) CmdIssueLinkTypes() error {
 	if err != nil {
 		return err
 	}
-	return runTemplate(c.getTemplate("issuelinktypes"), data, nil)
+	return runTemplate(c.getTemplate("issuelinktypes"), data, nil)
+}
+
+// IssueLinkType is a type of link between issues, like "Blocks", where the
+// Outward and Inward fields are the descriptions of the link from each side,
+// like "blocks" and "is blocked by".
+type IssueLinkType struct {
+	ID      string `json:"id,omitempty" yaml:"id,omitempty"`
+	Name    string `json:"name,omitempty" yaml:"name,omitempty"`
+	Inward  string `json:"inward,omitempty" yaml:"inward,omitempty"`
+	Outward string `json:"outward,omitempty" yaml:"outward,omitempty"`
+}
+
+// IssueLinkTypes will return the link types available on the jira service
+func (c *Cli) IssueLinkTypes() ([]*IssueLinkType, error) {
+	uri := fmt.Sprintf("%s/rest/api/2/issueLinkType", c.endpoint)
+	resp, err := c.get(uri)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		err := fmt.Errorf("Unexpected Response From GET: %s", resp.Status)
+		log.Errorf("%s", err)
+		return nil, err
+	}
+	results := struct {
+		IssueLinkTypes []*IssueLinkType `json:"issueLinkTypes"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, err
+	}
+	return results.IssueLinkTypes, nil
+}
+
+// FindIssueLinkType will find the link type where the name is either the
+// link type name, the outward description or the inward description (case
+// insensitive).  The returned bool is true when the name matched the inward
+// description, meaning the issues need to be swapped to get the link direction.
+func (c *Cli) FindIssueLinkType(name string) (*IssueLinkType, bool, error) {
+	linkTypes, err := c.IssueLinkTypes()
+	if err != nil {
+		return nil, false, err
+	}
+	for _, linkType := range linkTypes {
+		if strings.EqualFold(linkType.Name, name) || strings.EqualFold(linkType.Outward, name) {
+			return linkType, false, nil
+		}
+	}
+	for _, linkType := range linkTypes {
+		if strings.EqualFold(linkType.Inward, name) {
+			return linkType, true, nil
+		}
+	}
+	found := make([]string, 0, len(linkTypes))
+	for _, linkType := range linkTypes {
+		found = append(found, fmt.Sprintf("%s (%s/%s)", linkType.Name, linkType.Outward, linkType.Inward))
+	}
+	err = fmt.Errorf("Invalid link type '%s', Available: %s", name, strings.Join(found, ", "))
+	log.Errorf("%s", err)
+	return nil, false, err
+}
+
+// issueLinks will return the links for the given issue
+func (c *Cli) issueLinks(issue string) ([]interface{}, error) {
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s?fields=issuelinks", c.endpoint, issue)
+	data, err := responseToJSON(c.get(uri))
+	if err != nil {
+		return nil, err
+	}
+	if issueData, ok := data.(map[string]interface{}); ok {
+		if fields, ok := issueData["fields"].(map[string]interface{}); ok {
+			if links, ok := fields["issuelinks"].([]interface{}); ok {
+				return links, nil
+			}
+		}
+	}
+	err = fmt.Errorf("Unable to get links for issue %s", issue)
+	log.Errorf("%s", err)
+	return nil, err
+}
+
+// CmdLinks will send the links for the given issue, grouped by the link
+// description (like "blocks" or "is blocked by"), to the "links" template
+func (c *Cli) CmdLinks(issue string) error {
+	log.Debugf("links called")
+	c.Browse(issue)
+	links, err := c.issueLinks(issue)
+	if err != nil {
+		return err
+	}
+
+	groups := []map[string]interface{}{}
+	groupsByName := map[string]map[string]interface{}{}
+	for _, l := range links {
+		link, ok := l.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		linkType, _ := link["type"].(map[string]interface{})
+		description, direction := linkType["outward"], "outward"
+		other, ok := link["outwardIssue"].(map[string]interface{})
+		if !ok {
+			description, direction = linkType["inward"], "inward"
+			other, _ = link["inwardIssue"].(map[string]interface{})
+		}
+		groupName := fmt.Sprintf("%v", description)
+		group, ok := groupsByName[groupName]
+		if !ok {
+			group = map[string]interface{}{
+				"type":        linkType["name"],
+				"description": description,
+				"direction":   direction,
+				"links":       []interface{}{},
+			}
+			groupsByName[groupName] = group
+			groups = append(groups, group)
+		}
+		group["links"] = append(group["links"].([]interface{}), map[string]interface{}{
+			"id":    link["id"],
+			"issue": other,
+		})
+	}
+
+	return runTemplate(c.getTemplate("links"), map[string]interface{}{
+		"issue": issue,
+		"links": groups,
+	}, nil)
+}
+
+// CmdUnlink will remove the links between issue and other.  If a link type
+// is provided (by name or description) then only links of that type are
+// removed, otherwise all links between the two issues are removed.
+func (c *Cli) CmdUnlink(issue string, other string, issueLinkTypeName string) error {
+	log.Debugf("unlink called")
+	var linkType *IssueLinkType
+	if issueLinkTypeName != "" {
+		var err error
+		if linkType, _, err = c.FindIssueLinkType(issueLinkTypeName); err != nil {
+			return err
+		}
+	}
+
+	links, err := c.issueLinks(issue)
+	if err != nil {
+		return err
+	}
+	ids := []string{}
+	for _, l := range links {
+		link, ok := l.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if linkType != nil {
+			if t, ok := link["type"].(map[string]interface{}); !ok || t["id"] != linkType.ID {
+				continue
+			}
+		}
+		for _, side := range []string{"inwardIssue", "outwardIssue"} {
+			if linked, ok := link[side].(map[string]interface{}); ok && strings.EqualFold(fmt.Sprintf("%v", linked["key"]), other) {
+				ids = append(ids, fmt.Sprintf("%v", link["id"]))
+			}
+		}
+	}
+	if len(ids) == 0 {
+		err := fmt.Errorf("No links found between %s and %s", issue, other)
+		log.Errorf("%s", err)
+		return err
+	}
+
+	for _, id := range ids {
+		uri := fmt.Sprintf("%s/rest/api/2/issueLink/%s", c.endpoint, id)
+		if c.getOptBool("dryrun", false) {
+			log.Debugf("DELETE: %s", uri)
+			log.Debugf("Dryrun mode, skipping DELETE")
+			continue
+		}
+		resp, err := c.delete(uri)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != 204 {
+			logBuffer := bytes.NewBuffer(make([]byte, 0))
+			resp.Write(logBuffer)
+			err := fmt.Errorf("Unexpected Response From DELETE")
+			log.Errorf("%s:\n%s", err, logBuffer)
+			return err
+		}
+	}
+	c.Browse(issue)
+	if !c.GetOptBool("quiet", false) {
+		fmt.Printf("OK %s %s/browse/%s\n", issue, c.endpoint, issue)
+	}
+	return nil
 }
 
 // CmdIssueLink is a generic function for adding a link type to an issue
+// The issueLinkTypeName can be the link type name, or the outward or inward
+// description, so "blocks" and "is blocked by" will both work with the
+// link direction adjusted accordingly.
 func (c *Cli) CmdIssueLink(inwardIssue string, issueLinkTypeName string, outwardIssue string) error {
 	log.Debugf("issuelink called")
 
+	linkType, reverse, err := c.FindIssueLinkType(issueLinkTypeName)
+	if err != nil {
+		return err
+	}
+	issue := inwardIssue
+	if reverse {
+		inwardIssue, outwardIssue = outwardIssue, inwardIssue
+	}
+
+	if err := c.linkIssues(linkType.Name, inwardIssue, outwardIssue); err != nil {
+		return err
+	}
+	c.Browse(issue)
+	if !c.GetOptBool("quiet", false) {
+		fmt.Printf("OK %s %s/browse/%s\n", issue, c.endpoint, issue)
+	}
+	return nil
+}
+
+// linkIssues will create a link of the named type from inwardIssue to outwardIssue
+func (c *Cli) linkIssues(issueLinkTypeName string, inwardIssue string, outwardIssue string) error {
 	json, err := jsonEncode(map[string]interface{}{
 		"type": map[string]string{
 			"name": issueLinkTypeName,
@@ -560,12 +1506,7 @@ func (c *Cli) CmdIssueLink(inwardIssue string, issueLinkTypeName string, outward
 	if err != nil {
 		return err
 	}
-	if resp.StatusCode == 201 {
-		c.Browse(inwardIssue)
-		if !c.GetOptBool("quiet", false) {
-			fmt.Printf("OK %s %s/browse/%s\n", inwardIssue, c.endpoint, inwardIssue)
-		}
-	} else {
+	if resp.StatusCode != 201 {
 		logBuffer := bytes.NewBuffer(make([]byte, 0))
 		resp.Write(logBuffer)
 		err := fmt.Errorf("Unexpected Response From POST")
@@ -621,18 +1562,29 @@ func (c *Cli) CmdBlocks(blocker string, issue string) error {
 
 // CmdDups will update the given issue as being a duplicate by the given dup issue
 // and will attempt to resolve the dup issue
+// The link type name varies across jira installs, it can be set with
+// the 'duplicateType' option and defaults to "Duplicate".
 func (c *Cli) CmdDups(duplicate string, issue string) error {
 	log.Debugf("dups called")
 
+	linkType, reverse, err := c.FindIssueLinkType(c.GetOptString("duplicateType", "Duplicate"))
+	if err != nil {
+		return err
+	}
+	inwardIssue, outwardIssue := duplicate, issue
+	if reverse {
+		inwardIssue, outwardIssue = outwardIssue, inwardIssue
+	}
+
 	json, err := jsonEncode(map[string]interface{}{
 		"type": map[string]string{
-			"name": "Duplicate", // TODO This is probably not constant across Jira installs
+			"name": linkType.Name,
 		},
 		"inwardIssue": map[string]string{
-			"key": duplicate,
+			"key": inwardIssue,
 		},
 		"outwardIssue": map[string]string{
-			"key": issue,
+			"key": outwardIssue,
 		},
 	})
 	if err != nil {
@@ -784,40 +1736,100 @@ func (c *Cli) CmdRankBefore(issue, before string) error {
 	return nil
 }
 
+// chooseTransition will return the transition with the given name, or the
+// only transition containing the name.  When several transitions contain the
+// name the user is asked to choose between them, or an error is returned when
+// not on a terminal.
+func chooseTransition(transitions jiradata.Transitions, name string) (*jiradata.Transition, error) {
+	candidates := transitions.Matches(name)
+	switch len(candidates) {
+	case 0:
+		found := make([]string, 0, len(transitions))
+		for _, trans := range transitions {
+			found = append(found, trans.Name)
+		}
+		return nil, fmt.Errorf("Invalid Transition '%s', Available: %s", name, strings.Join(found, ", "))
+	case 1:
+		return candidates[0], nil
+	}
+	choices := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		to := ""
+		if candidate.To != nil {
+			to = candidate.To.Name
+		}
+		choices = append(choices, fmt.Sprintf("%s (to %s)", candidate.Name, to))
+	}
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return nil, fmt.Errorf("Ambiguous Transition '%s', Matches: %s", name, strings.Join(choices, ", "))
+	}
+	choice, err := promptChoice(fmt.Sprintf("Transition '%s' is ambiguous", name), choices)
+	if err != nil {
+		return nil, err
+	}
+	return candidates[choice], nil
+}
+
 // CmdTransition will move state of the given issue to the given transtion
 func (c *Cli) CmdTransition(issue string, trans string) error {
 	log.Debugf("transition called")
-	uri := fmt.Sprintf("%s/rest/api/2/issue/%s/transitions?expand=transitions.fields", c.endpoint, issue)
-	data, err := responseToJSON(c.get(uri))
+	issueData, err := c.issueWithTransitions(issue)
 	if err != nil {
 		return err
 	}
+	return c.transition(issue, issueData, trans)
+}
+
+// issueWithTransitions will return the issue data along with the
+// "transitions" available for it, so transitioning only needs one request
+func (c *Cli) issueWithTransitions(issue string) (map[string]interface{}, error) {
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s?expand=transitions.fields", c.endpoint, issue)
+	data, err := responseToJSON(c.get(uri))
+	if err != nil {
+		return nil, err
+	}
+	issueData, ok := data.(map[string]interface{})
+	if !ok {
+		err := fmt.Errorf("Unexpected response for issue %s", issue)
+		log.Errorf("%s", err)
+		return nil, err
+	}
+	return issueData, nil
+}
+
+// issueTransitions will return the transitions of the issue data from
+// issueWithTransitions
+func issueTransitions(issueData map[string]interface{}) jiradata.Transitions {
+	transitions := jiradata.Transitions{}
+	if content, err := jsonEncode(issueData["transitions"]); err == nil {
+		json.Unmarshal([]byte(content), &transitions)
+	}
+	return transitions
+}
+
+// transition will move the issue with the issue data from
+// issueWithTransitions to the given transition
+func (c *Cli) transition(issue string, issueData map[string]interface{}, trans string) error {
+	profile := c.issueWorkflowProfile(issueData)
+	trans = c.transitionAlias(issueData, profile, trans)
 
-	transitions := data.(map[string]interface{})["transitions"].([]interface{})
-	var transID, transName string
+	chosen, err := chooseTransition(issueTransitions(issueData), trans)
+	if err != nil {
+		log.Debugf("%s", err)
+		return err
+	}
 	var transMeta map[string]interface{}
-	found := make([]string, 0, len(transitions))
+	transitions, _ := issueData["transitions"].([]interface{})
 	for _, transition := range transitions {
-		name := transition.(map[string]interface{})["name"].(string)
-		id := transition.(map[string]interface{})["id"].(string)
-		found = append(found, name)
-		if strings.Contains(strings.ToLower(name), strings.ToLower(trans)) {
-			transName = name
-			transID = id
+		if transition.(map[string]interface{})["id"] == chosen.ID {
 			transMeta = transition.(map[string]interface{})
-			if strings.ToLower(name) == strings.ToLower(trans) {
-				break
-			}
 		}
 	}
-	if transID == "" {
-		err := fmt.Errorf("Invalid Transition '%s', Available: %s", trans, strings.Join(found, ", "))
-		log.Debugf("%s", err)
-		return err
-	}
+	transName := transMeta["name"].(string)
+	transID := transMeta["id"].(string)
 
 	handlePost := func(json string) error {
-		uri = fmt.Sprintf("%s/rest/api/2/issue/%s/transitions", c.endpoint, issue)
+		uri := fmt.Sprintf("%s/rest/api/2/issue/%s/transitions", c.endpoint, issue)
 		if c.getOptBool("dryrun", false) {
 			log.Debugf("POST: %s", json)
 			log.Debugf("Dryrun mode, skipping POST")
@@ -842,13 +1854,10 @@ func (c *Cli) CmdTransition(issue string, trans string) error {
 		return nil
 	}
 
-	uri = fmt.Sprintf("%s/rest/api/2/issue/%s", c.endpoint, issue)
-	data, err = responseToJSON(c.get(uri))
-	if err != nil {
-		return err
-	}
-	issueData := data.(map[string]interface{})
 	issueData["meta"] = transMeta
+	if c.GetOptString("defaultResolution", "") == "" && profile.DefaultResolution != "" {
+		c.opts["defaultResolution"] = profile.DefaultResolution
+	}
 	if c.GetOptString("defaultResolution", "") == "" {
 		// .meta.fields.resolution.allowedValues
 		if fields, ok := transMeta["fields"].(map[string]interface{}); ok {
@@ -872,6 +1881,11 @@ func (c *Cli) CmdTransition(issue string, trans string) error {
 		"name": transName,
 		"id":   transID,
 	}
+	issueData["defaults"] = map[string]interface{}{
+		"transition": map[string]interface{}{
+			"id": transID,
+		},
+	}
 	return c.editTemplate(
 		c.getTemplate("transition"),
 		fmt.Sprintf("%s-trans-%s-", issue, trans),
@@ -880,11 +1894,105 @@ func (c *Cli) CmdTransition(issue string, trans string) error {
 	)
 }
 
+// transitionAlias will return the transition name to use for trans.  The
+// 'transitions' of the workflow profile are checked first, then the
+// 'transitionAliases' option, which maps project keys to a map of alias to
+// transition name, and finally the default names for the shortcut commands.
+// If there is no alias then trans is returned unmodified.
+func (c *Cli) transitionAlias(issueData map[string]interface{}, profile *WorkflowProfile, trans string) string {
+	if alias, ok := profile.Transitions[trans]; ok {
+		log.Debugf("Using transition %q for %q from workflow profile", alias, trans)
+		return alias
+	}
+	if raw, ok := c.opts["transitionAliases"]; ok {
+		fixed, err := yamlFixup(raw)
+		if err != nil {
+			log.Warningf("Invalid transitionAliases option: %s", err)
+			return trans
+		}
+		aliases, _ := fixed.(map[string]interface{})
+		project := issueField(issueData, "project", "key")
+		if projectAliases, ok := aliases[project].(map[string]interface{}); ok {
+			if alias, ok := projectAliases[trans].(string); ok {
+				log.Debugf("Using transition %q for %q in %s", alias, trans, project)
+				return alias
+			}
+		}
+	}
+	if alias, ok := shortcutTransitions[trans]; ok {
+		return alias
+	}
+	return trans
+}
+
+// transitionIssue will apply the transition to the issue without opening an
+// editor, optionally adding a comment.  When the transition has a resolution
+// field it is set from defaultResolution.
+func (c *Cli) transitionIssue(issue string, trans *jiradata.Transition, profile *WorkflowProfile, comment string) error {
+	request := map[string]interface{}{
+		"transition": map[string]interface{}{
+			"id": trans.ID,
+		},
+	}
+	if comment != "" {
+		request["update"] = map[string]interface{}{
+			"comment": []interface{}{
+				map[string]interface{}{
+					"add": map[string]interface{}{
+						"body": comment,
+					},
+				},
+			},
+		}
+	}
+	if meta, ok := trans.Fields["resolution"]; ok {
+		if resolution := c.defaultResolution(profile); resolution != "" {
+			request["fields"] = map[string]interface{}{
+				"resolution": map[string]interface{}{
+					"name": resolution,
+				},
+			}
+		} else if meta.Required && !meta.HasDefaultValue {
+			err := fmt.Errorf("Transition '%s' of %s requires a resolution, set the defaultResolution of the workflow profile (see 'jira workflow discover')", trans.Name, issue)
+			log.Errorf("%s", err)
+			return err
+		}
+	}
+
+	json, err := jsonEncode(request)
+	if err != nil {
+		return err
+	}
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s/transitions", c.endpoint, issue)
+	if c.getOptBool("dryrun", false) {
+		log.Debugf("POST: %s", json)
+		log.Debugf("Dryrun mode, skipping POST")
+		return nil
+	}
+	resp, err := c.post(uri, json)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 204 {
+		logBuffer := bytes.NewBuffer(make([]byte, 0))
+		resp.Write(logBuffer)
+		err := fmt.Errorf("Unexpected Response From POST")
+		log.Errorf("%s:\n%s", err, logBuffer)
+		return err
+	}
+	return nil
+}
+
 // CmdComment will open up editor with "comment" template and submit
 // YAML output to jira
 func (c *Cli) CmdComment(issue string) error {
 	log.Debugf("comment called")
 
+	attachments := c.getOptStrings("attach")
+	if err := checkAttachFiles(attachments); err != nil {
+		return err
+	}
+	posted := false
 	handlePost := func(json string) error {
 		uri := fmt.Sprintf("%s/rest/api/2/issue/%s/comment", c.endpoint, issue)
 		if c.getOptBool("dryrun", false) {
@@ -898,6 +2006,192 @@ func (c *Cli) CmdComment(issue string) error {
 		}
 
 		if resp.StatusCode == 201 {
+			posted = true
+			return nil
+		}
+		logBuffer := bytes.NewBuffer(make([]byte, 0))
+		resp.Write(logBuffer)
+		err = fmt.Errorf("Unexpected Response From POST")
+		log.Errorf("%s:\n%s", err, logBuffer)
+		return err
+	}
+	// the attachments are uploaded once the comment is posted, so a failed
+	// upload never offers to edit and post the comment again
+	commented := func(err error) error {
+		if err != nil || !posted {
+			return err
+		}
+		if err := c.attachFiles(issue, attachments); err != nil {
+			return err
+		}
+		c.Browse(issue)
+		if !c.GetOptBool("quiet", false) {
+			fmt.Printf("OK %s %s/browse/%s\n", issue, c.endpoint, issue)
+		}
+		return nil
+	}
+
+	visibility, err := c.commentVisibility()
+	if err != nil {
+		return err
+	}
+
+	if comment, ok := c.opts["comment"]; ok && comment != "" {
+		commentData := map[string]interface{}{
+			"body": comment,
+		}
+		if visibility != nil {
+			commentData["visibility"] = visibility
+		}
+		json, err := jsonEncode(commentData)
+		if err != nil {
+			return err
+		}
+		return commented(handlePost(json))
+	}
+	return commented(c.editTemplate(
+		c.getTemplate("comment"),
+		fmt.Sprintf("%s-create-", issue),
+		map[string]interface{}{
+			"issue":      issue,
+			"overrides":  c.opts,
+			"visibility": visibility,
+		},
+		handlePost,
+	))
+}
+
+// defaultResolution will return the 'defaultResolution' option or the
+// default resolution of the workflow profile
+func (c *Cli) defaultResolution(profile *WorkflowProfile) string {
+	return c.GetOptString("defaultResolution", profile.DefaultResolution)
+}
+
+// addComment will add a plain comment to the issue
+func (c *Cli) addComment(issue string, body string) error {
+	json, err := jsonEncode(map[string]interface{}{
+		"body": body,
+	})
+	if err != nil {
+		return err
+	}
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s/comment", c.endpoint, issue)
+	if c.getOptBool("dryrun", false) {
+		log.Debugf("POST: %s", json)
+		log.Debugf("Dryrun mode, skipping POST")
+		return nil
+	}
+	resp, err := c.post(uri, json)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 201 {
+		logBuffer := bytes.NewBuffer(make([]byte, 0))
+		resp.Write(logBuffer)
+		err := fmt.Errorf("Unexpected Response From POST")
+		log.Errorf("%s:\n%s", err, logBuffer)
+		return err
+	}
+	return nil
+}
+
+// postCreated will POST the body, expecting the item to be created, and
+// return the created item
+func (c *Cli) postCreated(uri string, body interface{}) (interface{}, error) {
+	json, err := jsonEncode(body)
+	if err != nil {
+		return nil, err
+	}
+	if c.getOptBool("dryrun", false) {
+		log.Debugf("POST: %s", json)
+		log.Debugf("Dryrun mode, skipping POST")
+		return nil, nil
+	}
+	resp, err := c.post(uri, json)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 201 {
+		logBuffer := bytes.NewBuffer(make([]byte, 0))
+		resp.Write(logBuffer)
+		err := fmt.Errorf("Unexpected Response From POST")
+		log.Errorf("%s:\n%s", err, logBuffer)
+		return nil, err
+	}
+	return responseToJSON(resp, nil)
+}
+
+// issueComments will return all the comments of the issue
+func (c *Cli) issueComments(issue string) ([]interface{}, error) {
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s/comment?maxResults=1000", c.endpoint, issue)
+	data, err := responseToJSON(c.get(uri))
+	if err != nil {
+		return nil, err
+	}
+	comments, _ := data.(map[string]interface{})["comments"].([]interface{})
+	return comments, nil
+}
+
+// commentVisibility will parse the 'visibility' option, which is expected
+// to be in the form "role:NAME" or "group:NAME", into the structure jira
+// requires for restricting comments.  nil is returned when unset.
+func (c *Cli) commentVisibility() (map[string]interface{}, error) {
+	visibility := c.getOptString("visibility", "")
+	if visibility == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(visibility, ":", 2)
+	if len(parts) != 2 || parts[1] == "" || (parts[0] != "role" && parts[0] != "group") {
+		err := fmt.Errorf("Invalid visibility %q, must be role:NAME or group:NAME", visibility)
+		log.Errorf("%s", err)
+		return nil, err
+	}
+	return map[string]interface{}{
+		"type":  parts[0],
+		"value": parts[1],
+	}, nil
+}
+
+// CmdComments will send the comments for the given issue to the "comments" template
+func (c *Cli) CmdComments(issue string) error {
+	log.Debugf("comments called")
+	c.Browse(issue)
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s/comment", c.endpoint, issue)
+	data, err := responseToJSON(c.get(uri))
+	if err != nil {
+		return err
+	}
+	return runTemplate(c.getTemplate("comments"), data, nil)
+}
+
+// CmdCommentEdit will open the existing comment body in the "comment" template
+// for editing then submit the YAML output to jira
+func (c *Cli) CmdCommentEdit(issue string, id string) error {
+	log.Debugf("comment edit called")
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s/comment/%s", c.endpoint, issue, id)
+	data, err := responseToJSON(c.get(uri))
+	if err != nil {
+		return err
+	}
+	commentData, ok := data.(map[string]interface{})
+	if !ok || commentData["id"] == nil {
+		err := fmt.Errorf("Comment %s not found on %s", id, issue)
+		log.Errorf("%s", err)
+		return err
+	}
+
+	handlePut := func(json string) error {
+		if c.getOptBool("dryrun", false) {
+			log.Debugf("PUT: %s", json)
+			log.Debugf("Dryrun mode, skipping PUT")
+			return nil
+		}
+		resp, err := c.put(uri, json)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == 200 {
 			c.Browse(issue)
 			if !c.GetOptBool("quiet", false) {
 				fmt.Printf("OK %s %s/browse/%s\n", issue, c.endpoint, issue)
@@ -906,28 +2200,70 @@ func (c *Cli) CmdComment(issue string) error {
 		}
 		logBuffer := bytes.NewBuffer(make([]byte, 0))
 		resp.Write(logBuffer)
-		err = fmt.Errorf("Unexpected Response From POST")
+		err = fmt.Errorf("Unexpected Response From PUT")
 		log.Errorf("%s:\n%s", err, logBuffer)
 		return err
 	}
 
+	visibility, err := c.commentVisibility()
+	if err != nil {
+		return err
+	}
+	if visibility != nil {
+		commentData["visibility"] = visibility
+	}
+
 	if comment, ok := c.opts["comment"]; ok && comment != "" {
-		json, err := jsonEncode(map[string]interface{}{
+		edited := map[string]interface{}{
 			"body": comment,
-		})
+		}
+		if visibility != nil {
+			edited["visibility"] = visibility
+		}
+		json, err := jsonEncode(edited)
 		if err != nil {
 			return err
 		}
-		return handlePost(json)
+		return handlePut(json)
 	}
+
+	commentData["issue"] = issue
+	commentData["overrides"] = c.opts
 	return c.editTemplate(
 		c.getTemplate("comment"),
-		fmt.Sprintf("%s-create-", issue),
-		map[string]interface{}{},
-		handlePost,
+		fmt.Sprintf("%s-comment-%s-", issue, id),
+		commentData,
+		handlePut,
 	)
 }
 
+// CmdCommentRemove will delete the given comment id from the issue
+func (c *Cli) CmdCommentRemove(issue string, id string) error {
+	log.Debugf("comment rm called")
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s/comment/%s", c.endpoint, issue, id)
+	if c.getOptBool("dryrun", false) {
+		log.Debugf("DELETE: %s", uri)
+		log.Debugf("Dryrun mode, skipping DELETE")
+		return nil
+	}
+	resp, err := c.delete(uri)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == 204 {
+		c.Browse(issue)
+		if !c.GetOptBool("quiet", false) {
+			fmt.Printf("OK %s %s/browse/%s\n", issue, c.endpoint, issue)
+		}
+		return nil
+	}
+	logBuffer := bytes.NewBuffer(make([]byte, 0))
+	resp.Write(logBuffer)
+	err = fmt.Errorf("Unexpected Response From DELETE")
+	log.Errorf("%s:\n%s", err, logBuffer)
+	return err
+}
+
 // CmdComponent will add a new component to given project
 func (c *Cli) CmdComponent(action string, project string, name string, desc string, lead string) error {
 	log.Debugf("component called")
@@ -0,0 +1,100 @@
+package jira
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/Netflix-Skunkworks/go-jira.v0/data"
+)
+
+func testTransitions() jiradata.Transitions {
+	return jiradata.Transitions{
+		{ID: "1", Name: "Close as Won't Fix", To: &jiradata.Status{Name: "Closed"}},
+		{ID: "2", Name: "Close", To: &jiradata.Status{Name: "Closed"}},
+		{ID: "3", Name: "Start Progress", To: &jiradata.Status{Name: "In Progress"}},
+		{ID: "4", Name: "Stop Progress", To: &jiradata.Status{Name: "To Do"}},
+	}
+}
+
+func TestChooseTransitionExact(t *testing.T) {
+	trans, err := chooseTransition(testTransitions(), "close")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trans.ID != "2" {
+		t.Errorf("expected the exact match 'Close', got '%s'", trans.Name)
+	}
+}
+
+func TestChooseTransitionUnique(t *testing.T) {
+	trans, err := chooseTransition(testTransitions(), "start")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trans.ID != "3" {
+		t.Errorf("expected 'Start Progress', got '%s'", trans.Name)
+	}
+}
+
+func TestChooseTransitionAmbiguous(t *testing.T) {
+	// the tests are not run on a terminal, so there is no prompt
+	_, err := chooseTransition(testTransitions(), "progress")
+	if err == nil || !strings.HasPrefix(err.Error(), "Ambiguous Transition 'progress'") {
+		t.Errorf("expected an ambiguous transition error, got %v", err)
+	}
+}
+
+func TestChooseTransitionInvalid(t *testing.T) {
+	_, err := chooseTransition(testTransitions(), "reopen")
+	expected := "Invalid Transition 'reopen', Available: Close as Won't Fix, Close, Start Progress, Stop Progress"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
+
+func TestTransitionsFind(t *testing.T) {
+	// Find keeps returning the first transition containing the name
+	if trans := testTransitions().Find("close"); trans == nil || trans.ID != "1" {
+		t.Errorf("expected Find to return the first match 'Close as Won't Fix', got %v", trans)
+	}
+}
+
+func TestTransitionsMatchesExactFirst(t *testing.T) {
+	if matches := testTransitions().Matches("close"); len(matches) != 1 || matches[0].ID != "2" {
+		t.Errorf("expected Matches to prefer the exact match 'Close', got %v", matches)
+	}
+	if matches := testTransitions().Matches("progress"); len(matches) != 2 {
+		t.Errorf("expected both progress transitions, got %v", matches)
+	}
+}
+
+func TestWorklogEstimateParams(t *testing.T) {
+	c := New(map[string]interface{}{"adjust-estimate": "manual", "reduce-by": "1h", "increase-by": "2h"})
+	for action, expected := range map[string]string{
+		"add": "?adjustEstimate=manual&reduceBy=1h",
+		"rm":  "?adjustEstimate=manual&increaseBy=2h",
+	} {
+		if params, err := c.worklogEstimateParams(action); err != nil || params != expected {
+			t.Errorf("%s: expected %s, got %s %v", action, expected, params, err)
+		}
+	}
+	// jira only accepts new, leave and auto when updating a worklog
+	if _, err := c.worklogEstimateParams("edit"); err == nil {
+		t.Error("expected manual to be rejected for edit")
+	}
+	c.opts["adjust-estimate"] = "leave"
+	if params, err := c.worklogEstimateParams("edit"); err != nil || params != "?adjustEstimate=leave" {
+		t.Errorf("expected leave for edit, got %s %v", params, err)
+	}
+}
+
+func TestExpansionsRemoteLinks(t *testing.T) {
+	c := New(map[string]interface{}{"expand": "changelog,remotelinks"})
+	if !c.expanded("remotelinks") {
+		t.Error("expected the remotelinks expansion")
+	}
+	// remote links are fetched separately, so jira is not asked for them
+	if x := c.expansions("transitions"); strings.Join(x, ",") != "changelog,transitions" {
+		t.Errorf("expected changelog,transitions, got %v", x)
+	}
+}
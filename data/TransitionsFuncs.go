@@ -16,3 +16,20 @@ func (t Transitions) Find(name string) *Transition {
 	}
 	return nil
 }
+
+// Matches will return the transition with exactly the given
+// name (ignoring case), otherwise all the transitions that
+// contain the name.
+func (t Transitions) Matches(name string) Transitions {
+	name = strings.ToLower(name)
+	matches := Transitions{}
+	for _, trans := range t {
+		if strings.ToLower(trans.Name) == name {
+			return Transitions{trans}
+		}
+		if strings.Contains(strings.ToLower(trans.Name), name) {
+			matches = append(matches, trans)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,140 @@
+package jira
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const diffContext = 3
+
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// diffLines computes the line by line edit script to get from a to b using
+// the longest common subsequence of lines.
+func diffLines(a, b []string) []diffLine {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := make([]diffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		} else {
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{'+', b[j]})
+	}
+	return lines
+}
+
+func splitLines(content string) []string {
+	if content == "" {
+		return []string{}
+	}
+	content = strings.Replace(content, "\r\n", "\n", -1)
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// unifiedDiff will return the changes between from and to in unified diff
+// format (without the file headers), or an empty string if they are the same
+func unifiedDiff(from, to string) string {
+	lines := diffLines(splitLines(from), splitLines(to))
+
+	changed := false
+	for _, line := range lines {
+		if line.op != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	for start := 0; start < len(lines); {
+		// find the next change
+		for start < len(lines) && lines[start].op == ' ' {
+			start++
+		}
+		if start == len(lines) {
+			break
+		}
+		// extend the hunk until there are more than 2*context unchanged lines
+		end, same := start, 0
+		for i := start; i < len(lines); i++ {
+			if lines[i].op == ' ' {
+				same++
+				if same > 2*diffContext {
+					break
+				}
+			} else {
+				same = 0
+				end = i + 1
+			}
+		}
+		hunkStart := start - diffContext
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+		hunkEnd := end + diffContext
+		if hunkEnd > len(lines) {
+			hunkEnd = len(lines)
+		}
+
+		// line numbers for the hunk header are 1 based
+		fromLine, toLine := 1, 1
+		for _, line := range lines[:hunkStart] {
+			if line.op != '+' {
+				fromLine++
+			}
+			if line.op != '-' {
+				toLine++
+			}
+		}
+		fromCount, toCount := 0, 0
+		for _, line := range lines[hunkStart:hunkEnd] {
+			if line.op != '+' {
+				fromCount++
+			}
+			if line.op != '-' {
+				toCount++
+			}
+		}
+		fmt.Fprintf(buffer, "@@ -%d,%d +%d,%d @@\n", fromLine, fromCount, toLine, toCount)
+		for _, line := range lines[hunkStart:hunkEnd] {
+			fmt.Fprintf(buffer, "%c%s\n", line.op, line.text)
+		}
+		start = hunkEnd
+	}
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
@@ -0,0 +1,148 @@
+package jira
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnifiedDiffSame(t *testing.T) {
+	if diff := unifiedDiff("a\nb\n", "a\nb"); diff != "" {
+		t.Errorf("expected no diff, got %q", diff)
+	}
+}
+
+func TestUnifiedDiffChange(t *testing.T) {
+	diff := unifiedDiff("one\ntwo\nthree", "one\n2\nthree\nfour")
+	expected := strings.Join([]string{
+		"@@ -1,3 +1,4 @@",
+		" one",
+		"-two",
+		"+2",
+		" three",
+		"+four",
+	}, "\n")
+	if diff != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, diff)
+	}
+}
+
+func TestUnifiedDiffFromEmpty(t *testing.T) {
+	diff := unifiedDiff("", "new")
+	if expected := "@@ -1,0 +1,1 @@\n+new"; diff != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, diff)
+	}
+}
+
+func TestUnifiedDiffHunks(t *testing.T) {
+	from := []string{}
+	for i := 0; i < 20; i++ {
+		from = append(from, string(rune('a'+i)))
+	}
+	to := append([]string{}, from...)
+	to[1] = "B"
+	to[18] = "S"
+	diff := unifiedDiff(strings.Join(from, "\n"), strings.Join(to, "\n"))
+	expected := strings.Join([]string{
+		"@@ -1,5 +1,5 @@",
+		" a",
+		"-b",
+		"+B",
+		" c",
+		" d",
+		" e",
+		"@@ -16,5 +16,5 @@",
+		" p",
+		" q",
+		" r",
+		"-s",
+		"+S",
+		" t",
+	}, "\n")
+	if diff != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, diff)
+	}
+}
+
+func testHistories() []interface{} {
+	return []interface{}{
+		map[string]interface{}{
+			"author":  map[string]interface{}{"name": "mothra", "displayName": "Mothra"},
+			"created": "2017-01-30T10:00:00.000+0000",
+			"items": []interface{}{
+				map[string]interface{}{"field": "status", "fromString": "To Do", "toString": "In Progress"},
+				map[string]interface{}{"field": "description", "fromString": "old", "toString": "new"},
+			},
+		},
+		map[string]interface{}{
+			"author":  map[string]interface{}{"name": "gojira", "displayName": "Gojira"},
+			"created": "2017-02-01T10:00:00.000+0000",
+			"items": []interface{}{
+				map[string]interface{}{"field": "priority", "fromString": "Low", "toString": "High"},
+			},
+		},
+	}
+}
+
+func TestFilterHistoriesAuthor(t *testing.T) {
+	histories := filterHistories(testHistories(), nil, "Gojira", time.Time{})
+	if len(histories) != 1 {
+		t.Fatalf("expected 1 history, got %d", len(histories))
+	}
+	author := histories[0].(map[string]interface{})["author"].(map[string]interface{})
+	if author["name"] != "gojira" {
+		t.Errorf("expected the gojira history, got %v", author["name"])
+	}
+}
+
+func TestFilterHistoriesField(t *testing.T) {
+	histories := filterHistories(testHistories(), []string{" Description"}, "", time.Time{})
+	if len(histories) != 1 {
+		t.Fatalf("expected 1 history, got %d", len(histories))
+	}
+	items := histories[0].(map[string]interface{})["items"].([]interface{})
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+	item := items[0].(map[string]interface{})
+	if item["field"] != "description" {
+		t.Errorf("expected the description item, got %v", item["field"])
+	}
+	if expected := "@@ -1,1 +1,1 @@\n-old\n+new"; item["diff"] != expected {
+		t.Errorf("expected diff:\n%s\ngot:\n%v", expected, item["diff"])
+	}
+}
+
+func TestFilterHistoriesSince(t *testing.T) {
+	since := time.Date(2017, 1, 31, 0, 0, 0, 0, time.UTC)
+	histories := filterHistories(testHistories(), nil, "", since)
+	if len(histories) != 1 {
+		t.Fatalf("expected 1 history, got %d", len(histories))
+	}
+	if created := histories[0].(map[string]interface{})["created"]; created != "2017-02-01T10:00:00.000+0000" {
+		t.Errorf("expected the history after %s, got %v", since, created)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	before := time.Now()
+	since, err := parseSince("7d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := before.Sub(since); d < 7*24*time.Hour-time.Minute || d > 7*24*time.Hour+time.Minute {
+		t.Errorf("expected 7d ago, got %s ago", d)
+	}
+
+	since, err = parseSince("2017-01-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2017, 1, 31, 0, 0, 0, 0, time.Local); !since.Equal(expected) {
+		t.Errorf("expected %s, got %s", expected, since)
+	}
+
+	if _, err := parseSince("yesterday"); err == nil {
+		t.Error("expected an error for an invalid since")
+	}
+}
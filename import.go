@@ -0,0 +1,447 @@
+package jira
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/coryb/yaml.v2"
+)
+
+// importRow is an issue to be created by CmdImport
+type importRow struct {
+	ref    string                 // the "id" of the row, or its row number
+	parent string                 // ref of an earlier row, or an issue key
+	link   string                 // the field that links to the parent
+	record map[string]interface{} // the row as read, for the failure report
+	doc    map[string]interface{} // the issue document to create
+	depth  int
+	key    string
+	err    error
+}
+
+var yamlDocumentSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// readImportRecords will read the rows of a CSV file with a header row of
+// field names, a stream of YAML documents, a JSON array of objects, or JSON
+// Lines.  The format is chosen by the file extension, or guessed from the
+// content.
+func readImportRecords(file string) ([]map[string]interface{}, error) {
+	var content []byte
+	var err error
+	if file == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read %s: %s", file, err)
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), ".")
+	switch format {
+	case "csv", "jsonl", "ndjson", "json", "yml", "yaml":
+	default:
+		trimmed := bytes.TrimSpace(content)
+		firstLine := strings.SplitN(string(trimmed), "\n", 2)[0]
+		if bytes.HasPrefix(trimmed, []byte("[")) {
+			format = "json"
+		} else if bytes.HasPrefix(trimmed, []byte("{")) {
+			format = "jsonl"
+		} else if strings.HasPrefix(firstLine, "---") || strings.HasPrefix(firstLine, "#") || strings.Contains(firstLine, ": ") {
+			format = "yaml"
+		} else {
+			format = "csv"
+		}
+	}
+
+	records := []map[string]interface{}{}
+	switch format {
+	case "csv":
+		reader := csv.NewReader(bytes.NewReader(content))
+		reader.FieldsPerRecord = -1
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read CSV header from %s: %s", file, err)
+		}
+		for {
+			row, err := reader.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("Failed to parse CSV from %s: %s", file, err)
+			}
+			record := map[string]interface{}{}
+			for i, value := range row {
+				if i >= len(header) || strings.TrimSpace(value) == "" {
+					continue
+				}
+				name := strings.TrimSpace(header[i])
+				// repeated columns (eg several "Labels") become a list
+				switch existing := record[name].(type) {
+				case nil:
+					record[name] = value
+				case []interface{}:
+					record[name] = append(existing, value)
+				default:
+					record[name] = []interface{}{existing, value}
+				}
+			}
+			records = append(records, record)
+		}
+	case "json", "jsonl", "ndjson":
+		if bytes.HasPrefix(bytes.TrimSpace(content), []byte("[")) {
+			if err := json.Unmarshal(content, &records); err != nil {
+				return nil, fmt.Errorf("Failed to parse JSON from %s: %s", file, err)
+			}
+			break
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+			record := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(text), &record); err != nil {
+				return nil, fmt.Errorf("Failed to parse JSON from %s line %d: %s", file, line, err)
+			}
+			records = append(records, record)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("Failed to read %s: %s", file, err)
+		}
+	default:
+		for i, document := range yamlDocumentSeparator.Split(string(content), -1) {
+			record := map[string]interface{}{}
+			if err := yaml.Unmarshal([]byte(document), &record); err != nil {
+				return nil, fmt.Errorf("Failed to parse YAML document %d from %s: %s", i+1, file, err)
+			}
+			if len(record) == 0 {
+				continue
+			}
+			fixed, err := yamlFixup(record)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, fixed.(map[string]interface{}))
+		}
+	}
+	return records, nil
+}
+
+// importReference will return the string value of a reference like the row
+// "id" or the "parent", which may be given as a plain value or as an object
+// with a "key"
+func importReference(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case map[string]interface{}:
+		if key, ok := v["key"]; ok {
+			return fmt.Sprintf("%v", key)
+		}
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprintf("%v", value))
+}
+
+// importNamed will return a string property like the project key or the
+// issuetype name, which may be given as a plain value or as an object
+func importNamed(value interface{}, property string) string {
+	if v, ok := value.(map[string]interface{}); ok {
+		if name, ok := v[property]; ok {
+			return fmt.Sprintf("%v", name)
+		}
+		return ""
+	}
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// newImportRow will split a record into the row reference, the parent
+// reference and the issue document.  A record is either the fields of the
+// issue, or a document like the templates produce with "fields" (and
+// optionally "update").
+func newImportRow(number int, record map[string]interface{}) *importRow {
+	row := &importRow{
+		ref:    strconv.Itoa(number),
+		record: record,
+		doc:    map[string]interface{}{},
+	}
+	fields := map[string]interface{}{}
+	if f, ok := record["fields"].(map[string]interface{}); ok {
+		for name, value := range f {
+			fields[name] = value
+		}
+	}
+	if update, ok := record["update"].(map[string]interface{}); ok {
+		row.doc["update"] = update
+	}
+	for name, value := range record {
+		if name == "fields" || name == "update" {
+			if _, ok := value.(map[string]interface{}); ok {
+				continue
+			}
+		}
+		fields[name] = value
+	}
+	for name, value := range fields {
+		switch {
+		case strings.EqualFold(name, "id"):
+			if ref := importReference(value); ref != "" {
+				row.ref = ref
+			}
+		case strings.EqualFold(name, "parent"):
+			// a top level parent (as written to the failure report)
+			// takes precedence over a parent in the fields
+			if _, ok := record[name]; ok || row.parent == "" {
+				row.parent = importReference(value)
+			}
+		case strings.EqualFold(name, "project"):
+			fields["project"] = value
+			if name == "project" {
+				continue
+			}
+		case strings.EqualFold(name, "issuetype") || strings.EqualFold(name, "issue type"):
+			fields["issuetype"] = value
+			if name == "issuetype" {
+				continue
+			}
+		default:
+			continue
+		}
+		delete(fields, name)
+	}
+	row.doc["fields"] = fields
+	return row
+}
+
+// CmdImport will create an issue for each row of a CSV, YAML or JSON Lines
+// file.  Columns are fields given by id or display name, and the 'project'
+// and 'issuetype' options are used for rows without them.  The optional "id"
+// column names a row so that later rows can use it in their "parent" column
+// (eg epic -> story -> subtask), a parent may also be an existing issue key.
+// Issues are created with up to 'concurrency' (default: 4) requests at a time,
+// parents before their children.  Rows that fail are written to the
+// 'failures' file (default: FILE.failed.yml), with parents that were created
+// replaced by their key, so the import can be resumed by importing it.
+func (c *Cli) CmdImport(file string) error {
+	log.Debugf("import called")
+	records, err := readImportRecords(file)
+	if err != nil {
+		log.Errorf("%s", err)
+		return err
+	}
+
+	defaultProject := c.getOptString("project", "")
+	defaultIssueType := c.getOptString("issuetype", "")
+
+	rows := []*importRow{}
+	byRef := map[string]*importRow{}
+	metas := map[string]map[string]interface{}{}
+	for i, record := range records {
+		row := newImportRow(i+1, record)
+		rows = append(rows, row)
+		if _, ok := byRef[row.ref]; ok {
+			row.err = fmt.Errorf("duplicate id %s", row.ref)
+			continue
+		}
+		byRef[row.ref] = row
+
+		if parent, ok := byRef[row.parent]; ok {
+			row.depth = parent.depth + 1
+		}
+
+		fields := row.doc["fields"].(map[string]interface{})
+		project := strings.ToUpper(importNamed(fields["project"], "key"))
+		if project == "" {
+			project = defaultProject
+		}
+		issuetype := importNamed(fields["issuetype"], "name")
+		if issuetype == "" {
+			if defaultIssueType == "" && defaultProject != "" {
+				defaultIssueType = c.defaultIssueType()
+			}
+			issuetype = defaultIssueType
+		}
+		if project == "" || issuetype == "" {
+			row.err = fmt.Errorf("missing project or issuetype")
+			continue
+		}
+		fields["project"] = map[string]interface{}{"key": project}
+		fields["issuetype"] = map[string]interface{}{"name": issuetype}
+
+		metaKey := fmt.Sprintf("%s/%s", project, issuetype)
+		meta, ok := metas[metaKey]
+		if !ok {
+			data, err := c.createIssueMetaData(project, issuetype)
+			if err != nil {
+				row.err = err
+				continue
+			}
+			meta, _ = data.(map[string]interface{})
+			metas[metaKey] = meta
+		}
+		metaFields := fieldMetaMap(meta)
+		if row.parent != "" {
+			// subtasks (and any issue on jira cloud) use the parent
+			// field, otherwise the parent is the epic of the issue
+			row.link = "parent"
+			if _, ok := metaFields["parent"]; !ok {
+				if epicLink := c.resolveField("Epic Link", metaFields); epicLink != "" {
+					row.link = epicLink
+				}
+			}
+			// the parent key is not known yet, the reference is used
+			// so the required field is validated
+			fields[row.link] = row.parent
+		}
+		problems := c.applyFieldOverrides(row.doc, nil, metaFields)
+		problems = append(problems, coerceFields(row.doc, metaFields)...)
+		problems = append(problems, validateEdit(row.doc, metaFields)...)
+		if len(problems) > 0 {
+			row.err = fmt.Errorf("%s", strings.Join(problems, ", "))
+		}
+	}
+
+	maxDepth := 0
+	for _, row := range rows {
+		if row.depth > maxDepth {
+			maxDepth = row.depth
+		}
+	}
+	concurrency, _ := c.opts["concurrency"].(int)
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	// rows are created a level at a time so parents exist before their
+	// children are created
+	for depth := 0; depth <= maxDepth; depth++ {
+		var wg sync.WaitGroup
+		slots := make(chan bool, concurrency)
+		for _, row := range rows {
+			if row.depth != depth || row.err != nil {
+				continue
+			}
+			if parent, ok := byRef[row.parent]; ok {
+				if parent.err != nil {
+					row.err = fmt.Errorf("parent %s was not created", parent.ref)
+					continue
+				}
+			}
+			wg.Add(1)
+			slots <- true
+			go func(row *importRow) {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+				row.key, row.err = c.importIssue(row, byRef)
+			}(row)
+		}
+		wg.Wait()
+	}
+
+	failed := []*importRow{}
+	for _, row := range rows {
+		if row.err != nil {
+			log.Errorf("Row %s: %s", row.ref, row.err)
+			failed = append(failed, row)
+			continue
+		}
+		if !c.GetOptBool("quiet", false) && row.key != "" {
+			fmt.Printf("%s: %s %s/browse/%s\n", row.ref, row.key, c.endpoint, row.key)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	report := c.getOptString("failures", "")
+	if report == "" {
+		report = "import.failed.yml"
+		if file != "-" {
+			report = strings.TrimSuffix(file, filepath.Ext(file)) + ".failed.yml"
+		}
+	}
+	if err := writeImportFailures(report, failed, byRef); err != nil {
+		return err
+	}
+	err = fmt.Errorf("Failed to import %d of %d issues, import %s to retry them", len(failed), len(rows), report)
+	log.Errorf("%s", err)
+	return err
+}
+
+// importIssue will create the issue for the row, linking it to the parent
+func (c *Cli) importIssue(row *importRow, byRef map[string]*importRow) (string, error) {
+	if row.parent != "" {
+		parentKey := row.parent
+		if parent, ok := byRef[row.parent]; ok && parent.key != "" {
+			parentKey = parent.key
+		}
+		fields := row.doc["fields"].(map[string]interface{})
+		if row.link == "parent" {
+			fields["parent"] = map[string]interface{}{"key": parentKey}
+		} else {
+			fields[row.link] = parentKey
+		}
+	}
+	json, err := jsonEncode(row.doc)
+	if err != nil {
+		return "", err
+	}
+	return c.createIssue(json)
+}
+
+// writeImportFailures will write the failed rows as YAML documents with
+// the error as a comment.  Parents that were created are replaced with the
+// new key so the file can be imported again.
+func writeImportFailures(file string, failed []*importRow, byRef map[string]*importRow) error {
+	var buffer bytes.Buffer
+	for _, row := range failed {
+		record := map[string]interface{}{}
+		for name, value := range row.record {
+			if strings.EqualFold(name, "id") || strings.EqualFold(name, "parent") {
+				continue
+			}
+			record[name] = value
+		}
+		record["id"] = row.ref
+		if row.parent != "" {
+			record["parent"] = row.parent
+			if parent, ok := byRef[row.parent]; ok && parent.err == nil && parent.key != "" {
+				record["parent"] = parent.key
+			}
+		}
+		content, err := yaml.Marshal(record)
+		if err != nil {
+			return err
+		}
+		buffer.WriteString("---\n")
+		for _, line := range strings.Split(row.err.Error(), "\n") {
+			buffer.WriteString(fmt.Sprintf("# ERROR: %s\n", line))
+		}
+		buffer.Write(content)
+	}
+	if err := ioutil.WriteFile(file, buffer.Bytes(), 0644); err != nil {
+		err = fmt.Errorf("Failed to write %s: %s", file, err)
+		log.Errorf("%s", err)
+		return err
+	}
+	return nil
+}
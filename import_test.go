@@ -0,0 +1,45 @@
+package jira
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func readTestImport(t *testing.T, name, content string) []map[string]interface{} {
+	dir, err := ioutil.TempDir("", "jira-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	records, err := readImportRecords(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestReadImportRecordsJSON(t *testing.T) {
+	expected := []map[string]interface{}{
+		{"summary": "one"},
+		{"summary": "two", "labels": []interface{}{"a"}},
+	}
+	for name, content := range map[string]string{
+		"issues.json":  `[{"summary": "one"}, {"summary": "two", "labels": ["a"]}]`,
+		"issues.jsonl": "{\"summary\": \"one\"}\n\n{\"summary\": \"two\", \"labels\": [\"a\"]}\n",
+		// the json extension also accepts JSON Lines
+		"lines.json": "{\"summary\": \"one\"}\n{\"summary\": \"two\", \"labels\": [\"a\"]}",
+		// without an extension the format is guessed
+		"array": "\n  [{\"summary\": \"one\"},\n{\"summary\": \"two\", \"labels\": [\"a\"]}]\n",
+	} {
+		if records := readTestImport(t, name, content); !reflect.DeepEqual(records, expected) {
+			t.Errorf("%s: expected:\n%#v\ngot:\n%#v", name, expected, records)
+		}
+	}
+}
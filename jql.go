@@ -0,0 +1,827 @@
+package jira
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// JQLQuery is a parsed JQL query that can be evaluated against issue data,
+// so queries can be answered from the offline cache.  It covers the common
+// subset of JQL: comparisons of fields with values and functions, IN and NOT
+// IN lists, IS [NOT] EMPTY, ~ and !~ text search, AND, OR, NOT, parenthesis
+// and ORDER BY.  WAS and CHANGED need the issue history so they are not
+// supported.
+type JQLQuery struct {
+	where   jqlNode
+	orderBy []jqlOrder
+}
+
+// JQLEnv is what the evaluation of a query depends on
+type JQLEnv struct {
+	// User is the user for currentUser()
+	User string
+	// Now is the time for now() and relative dates like -7d
+	Now time.Time
+	// Resolve will return the field id for a field name, or an empty
+	// string when it is unknown
+	Resolve func(name string) string
+}
+
+type jqlNode interface {
+	match(env *JQLEnv, issue map[string]interface{}) (bool, error)
+}
+
+type jqlAnd struct{ left, right jqlNode }
+type jqlOr struct{ left, right jqlNode }
+type jqlNot struct{ node jqlNode }
+
+// jqlClause compares a field with the operands, operators are lower case
+// (eg "=", "not in", "is not")
+type jqlClause struct {
+	field    string
+	operator string
+	operands []jqlOperand
+}
+
+// jqlOperand is a value, EMPTY or a function call
+type jqlOperand struct {
+	value    string
+	empty    bool
+	function string
+	args     []string
+}
+
+type jqlOrder struct {
+	field string
+	desc  bool
+}
+
+type jqlTokenKind int
+
+const (
+	jqlEOF jqlTokenKind = iota
+	jqlWord
+	jqlString
+	jqlOperator
+	jqlLParen
+	jqlRParen
+	jqlComma
+)
+
+type jqlToken struct {
+	kind jqlTokenKind
+	text string
+}
+
+// is will return true for a word token matching the keyword
+func (t jqlToken) is(keyword string) bool {
+	return t.kind == jqlWord && strings.EqualFold(t.text, keyword)
+}
+
+func jqlTokenize(query string) ([]jqlToken, error) {
+	tokens := []jqlToken{}
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, jqlToken{jqlLParen, "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, jqlToken{jqlRParen, ")"})
+			i++
+		case r == ',':
+			tokens = append(tokens, jqlToken{jqlComma, ","})
+			i++
+		case r == '"' || r == '\'':
+			var buffer bytes.Buffer
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				buffer.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("Unterminated string in JQL: %s", query)
+			}
+			i++
+			tokens = append(tokens, jqlToken{jqlString, buffer.String()})
+		case strings.ContainsRune("=!~<>", r):
+			op := string(r)
+			if i+1 < len(runes) && (runes[i+1] == '=' || r == '!' && runes[i+1] == '~') {
+				op += string(runes[i+1])
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("Invalid operator '!' in JQL: %s", query)
+			}
+			tokens = append(tokens, jqlToken{jqlOperator, op})
+			i += len(op)
+		default:
+			start := i
+			for ; i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("()=!~<>,\"'", runes[i]); i++ {
+			}
+			tokens = append(tokens, jqlToken{jqlWord, string(runes[start:i])})
+		}
+	}
+	return append(tokens, jqlToken{kind: jqlEOF}), nil
+}
+
+type jqlParser struct {
+	tokens []jqlToken
+	pos    int
+	query  string
+}
+
+func (p *jqlParser) peek() jqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *jqlParser) next() jqlToken {
+	token := p.tokens[p.pos]
+	if token.kind != jqlEOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *jqlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s in JQL: %s", fmt.Sprintf(format, args...), p.query)
+}
+
+// ParseJQL will parse the JQL query
+func ParseJQL(query string) (*JQLQuery, error) {
+	tokens, err := jqlTokenize(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &jqlParser{tokens: tokens, query: query}
+	q := &JQLQuery{}
+	if t := p.peek(); t.kind != jqlEOF && !t.is("order") {
+		if q.where, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek().is("order") {
+		p.next()
+		if !p.next().is("by") {
+			return nil, p.errorf("Expected BY after ORDER")
+		}
+		for {
+			field := p.next()
+			if field.kind != jqlWord && field.kind != jqlString {
+				return nil, p.errorf("Expected a field to order by")
+			}
+			order := jqlOrder{field: field.text}
+			if p.peek().is("asc") {
+				p.next()
+			} else if p.peek().is("desc") {
+				p.next()
+				order.desc = true
+			}
+			q.orderBy = append(q.orderBy, order)
+			if p.peek().kind != jqlComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if t := p.peek(); t.kind != jqlEOF {
+		return nil, p.errorf("Unexpected '%s'", t.text)
+	}
+	return q, nil
+}
+
+func (p *jqlParser) parseOr() (jqlNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &jqlOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *jqlParser) parseAnd() (jqlNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &jqlAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *jqlParser) parseNot() (jqlNode, error) {
+	switch t := p.peek(); {
+	case t.is("not"):
+		p.next()
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &jqlNot{node}, nil
+	case t.kind == jqlLParen:
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != jqlRParen {
+			return nil, p.errorf("Expected ')'")
+		}
+		return node, nil
+	}
+	return p.parseClause()
+}
+
+func (p *jqlParser) parseClause() (jqlNode, error) {
+	field := p.next()
+	if field.kind != jqlWord && field.kind != jqlString {
+		return nil, p.errorf("Expected a field name, found '%s'", field.text)
+	}
+	clause := &jqlClause{field: field.text}
+	switch t := p.next(); {
+	case t.kind == jqlOperator:
+		clause.operator = t.text
+	case t.is("in"):
+		clause.operator = "in"
+	case t.is("not"):
+		if !p.next().is("in") {
+			return nil, p.errorf("Expected IN after NOT")
+		}
+		clause.operator = "not in"
+	case t.is("is"):
+		clause.operator = "is"
+		if p.peek().is("not") {
+			p.next()
+			clause.operator = "is not"
+		}
+	case t.is("was") || t.is("changed"):
+		return nil, p.errorf("%s needs the issue history and is not supported", strings.ToUpper(t.text))
+	default:
+		return nil, p.errorf("Expected an operator after %s", field.text)
+	}
+
+	switch clause.operator {
+	case "is", "is not":
+		t := p.next()
+		if !t.is("empty") && !t.is("null") {
+			return nil, p.errorf("Expected EMPTY after %s", strings.ToUpper(clause.operator))
+		}
+		clause.operands = []jqlOperand{{empty: true}}
+	case "in", "not in":
+		if p.peek().kind != jqlLParen {
+			// a function returning a list, like membersOf()
+			operand, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			clause.operands = []jqlOperand{operand}
+			break
+		}
+		p.next()
+		for {
+			operand, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			clause.operands = append(clause.operands, operand)
+			if t := p.next(); t.kind == jqlRParen {
+				break
+			} else if t.kind != jqlComma {
+				return nil, p.errorf("Expected ',' or ')' in list")
+			}
+		}
+	default:
+		operand, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		clause.operands = []jqlOperand{operand}
+	}
+	// like in jira, unresolved means the resolution is EMPTY
+	if strings.EqualFold(clause.field, "resolution") {
+		for i, operand := range clause.operands {
+			if operand.function == "" && strings.EqualFold(operand.value, "unresolved") {
+				clause.operands[i] = jqlOperand{empty: true}
+			}
+		}
+	}
+	return clause, nil
+}
+
+// the functions that can be evaluated locally
+var jqlFunctions = map[string]bool{
+	"now":          true,
+	"currentuser":  true,
+	"startofday":   true,
+	"endofday":     true,
+	"startofweek":  true,
+	"endofweek":    true,
+	"startofmonth": true,
+	"endofmonth":   true,
+	"startofyear":  true,
+	"endofyear":    true,
+}
+
+func (p *jqlParser) parseOperand() (jqlOperand, error) {
+	t := p.next()
+	switch {
+	case t.kind == jqlString:
+		return jqlOperand{value: t.text}, nil
+	case t.kind != jqlWord:
+		return jqlOperand{}, p.errorf("Expected a value, found '%s'", t.text)
+	case t.is("empty") || t.is("null"):
+		return jqlOperand{empty: true}, nil
+	case p.peek().kind != jqlLParen:
+		return jqlOperand{value: t.text}, nil
+	}
+	if !jqlFunctions[strings.ToLower(t.text)] {
+		return jqlOperand{}, p.errorf("Function %s() is not supported", t.text)
+	}
+	operand := jqlOperand{function: strings.ToLower(t.text)}
+	p.next()
+	if p.peek().kind == jqlRParen {
+		p.next()
+		return operand, nil
+	}
+	for {
+		arg := p.next()
+		if arg.kind != jqlWord && arg.kind != jqlString {
+			return jqlOperand{}, p.errorf("Expected an argument for %s()", t.text)
+		}
+		operand.args = append(operand.args, arg.text)
+		if t := p.next(); t.kind == jqlRParen {
+			break
+		} else if t.kind != jqlComma {
+			return jqlOperand{}, p.errorf("Expected ',' or ')' after arguments")
+		}
+	}
+	return operand, nil
+}
+
+// Match will return true when the issue matches the query
+func (q *JQLQuery) Match(env *JQLEnv, issue map[string]interface{}) (bool, error) {
+	if q.where == nil {
+		return true, nil
+	}
+	return q.where.match(env, issue)
+}
+
+// Sort will order the issues by the ORDER BY clause of the query, or by key
+// without one.  Issues without a value are last in ascending order.
+func (q *JQLQuery) Sort(env *JQLEnv, issues []interface{}) {
+	orderBy := q.orderBy
+	if len(orderBy) == 0 {
+		orderBy = []jqlOrder{{field: "key"}}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, _ := issues[i].(map[string]interface{})
+		b, _ := issues[j].(map[string]interface{})
+		for _, order := range orderBy {
+			cmp := env.sortCompare(order.field, env.values(a, order.field), env.values(b, order.field))
+			if cmp == 0 {
+				continue
+			}
+			if order.desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+}
+
+func (n *jqlAnd) match(env *JQLEnv, issue map[string]interface{}) (bool, error) {
+	if ok, err := n.left.match(env, issue); !ok || err != nil {
+		return false, err
+	}
+	return n.right.match(env, issue)
+}
+
+func (n *jqlOr) match(env *JQLEnv, issue map[string]interface{}) (bool, error) {
+	if ok, err := n.left.match(env, issue); ok || err != nil {
+		return ok, err
+	}
+	return n.right.match(env, issue)
+}
+
+func (n *jqlNot) match(env *JQLEnv, issue map[string]interface{}) (bool, error) {
+	ok, err := n.node.match(env, issue)
+	return !ok, err
+}
+
+func (n *jqlClause) match(env *JQLEnv, issue map[string]interface{}) (bool, error) {
+	values := env.values(issue, n.field)
+	operands := make([]string, 0, len(n.operands))
+	hasEmpty := false
+	for _, operand := range n.operands {
+		if operand.empty {
+			hasEmpty = true
+			continue
+		}
+		value, err := env.operandValue(operand)
+		if err != nil {
+			return false, err
+		}
+		operands = append(operands, value)
+	}
+
+	// any value equal to any operand
+	anyEqual := func() (bool, error) {
+		for _, value := range values {
+			for _, operand := range operands {
+				if equal, err := env.equal(value, operand); equal || err != nil {
+					return equal, err
+				}
+			}
+		}
+		return false, nil
+	}
+
+	switch n.operator {
+	case "is", "=":
+		if hasEmpty {
+			return len(values) == 0, nil
+		}
+		return anyEqual()
+	case "is not", "!=":
+		if hasEmpty {
+			return len(values) > 0, nil
+		}
+		// like jira, fields without a value never match !=
+		equal, err := anyEqual()
+		return len(values) > 0 && !equal, err
+	case "in":
+		if hasEmpty && len(values) == 0 {
+			return true, nil
+		}
+		return anyEqual()
+	case "not in":
+		if len(values) == 0 {
+			return false, nil
+		}
+		equal, err := anyEqual()
+		return !equal, err
+	case "~", "!~":
+		text := strings.ToLower(strings.Join(values, " "))
+		found := len(values) > 0
+		for _, word := range strings.Fields(strings.ToLower(strings.Join(operands, " "))) {
+			if !strings.Contains(text, strings.Trim(word, "*?")) {
+				found = false
+			}
+		}
+		return found == (n.operator == "~"), nil
+	case "<", "<=", ">", ">=":
+		for _, value := range values {
+			for _, operand := range operands {
+				cmp, err := env.compare(n.field, value, operand)
+				if err != nil {
+					return false, err
+				}
+				switch {
+				case n.operator == "<" && cmp < 0, n.operator == "<=" && cmp <= 0,
+					n.operator == ">" && cmp > 0, n.operator == ">=" && cmp >= 0:
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("Unsupported JQL operator '%s'", n.operator)
+}
+
+// the JQL names of the fields that differ from the field ids
+var jqlFieldAliases = map[string]string{
+	"affectedversion": "versions",
+	"component":       "components",
+	"createddate":     "created",
+	"due":             "duedate",
+	"fixversion":      "fixVersions",
+	"resolved":        "resolutiondate",
+	"type":            "issuetype",
+	"updateddate":     "updated",
+}
+
+var jqlCustomFieldRegexp = regexp.MustCompile(`^(?i)cf\[(\d+)\]$`)
+
+// values will return the values of the field of the issue as text, objects
+// are represented by their identifying properties so 'status = Done' and
+// 'status = 10001' both match
+func (env *JQLEnv) values(issue map[string]interface{}, field string) []string {
+	fields, _ := issue["fields"].(map[string]interface{})
+	switch name := strings.ToLower(field); name {
+	case "key", "issuekey", "issue":
+		return jqlValues(issue["key"])
+	case "id":
+		return jqlValues(issue["id"])
+	case "statuscategory":
+		status, _ := fields["status"].(map[string]interface{})
+		return jqlValues(status["statusCategory"])
+	case "comment":
+		return jqlComments(fields["comment"])
+	case "text":
+		values := []string{}
+		for _, id := range []string{"summary", "description", "environment"} {
+			values = append(values, jqlValues(fields[id])...)
+		}
+		return append(values, jqlComments(fields["comment"])...)
+	}
+	return jqlValues(fields[env.fieldID(field)])
+}
+
+func (env *JQLEnv) fieldID(field string) string {
+	if id, ok := jqlFieldAliases[strings.ToLower(field)]; ok {
+		return id
+	}
+	if match := jqlCustomFieldRegexp.FindStringSubmatch(field); match != nil {
+		return "customfield_" + match[1]
+	}
+	if env.Resolve != nil {
+		if id := env.Resolve(field); id != "" {
+			return id
+		}
+	}
+	return field
+}
+
+func jqlComments(value interface{}) []string {
+	comment, _ := value.(map[string]interface{})
+	comments, _ := comment["comments"].([]interface{})
+	bodies := []string{}
+	for _, c := range comments {
+		if c, ok := c.(map[string]interface{}); ok {
+			bodies = append(bodies, jqlValues(c["body"])...)
+		}
+	}
+	return bodies
+}
+
+func jqlValues(value interface{}) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	case []interface{}:
+		values := []string{}
+		for _, item := range v {
+			values = append(values, jqlValues(item)...)
+		}
+		return values
+	case map[string]interface{}:
+		values := []string{}
+		for _, key := range []string{"key", "name", "value", "displayName", "emailAddress", "accountId", "id"} {
+			if _, ok := v[key]; ok {
+				values = append(values, formatValue(v[key]))
+			}
+		}
+		return values
+	}
+	return []string{formatValue(value)}
+}
+
+// operandValue will return the value of the operand, evaluating functions
+func (env *JQLEnv) operandValue(operand jqlOperand) (string, error) {
+	if operand.function == "" {
+		return operand.value, nil
+	}
+	if operand.function == "currentuser" {
+		return env.User, nil
+	}
+	t := env.Now
+	if len(operand.args) > 0 {
+		period := strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(operand.function, "start"), "end"), "of")
+		unit := map[string]string{"week": "w", "month": "M", "year": "y"}[period]
+		if unit == "" {
+			unit = "d"
+		}
+		var err error
+		if t, err = jqlRelative(t, operand.args[0], unit); err != nil {
+			return "", err
+		}
+	}
+	switch operand.function {
+	case "startofday", "endofday":
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		if operand.function == "endofday" {
+			t = t.AddDate(0, 0, 1)
+		}
+	case "startofweek", "endofweek":
+		t = time.Date(t.Year(), t.Month(), t.Day()-int(t.Weekday()), 0, 0, 0, 0, t.Location())
+		if operand.function == "endofweek" {
+			t = t.AddDate(0, 0, 7)
+		}
+	case "startofmonth", "endofmonth":
+		t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		if operand.function == "endofmonth" {
+			t = t.AddDate(0, 1, 0)
+		}
+	case "startofyear", "endofyear":
+		t = time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
+		if operand.function == "endofyear" {
+			t = t.AddDate(1, 0, 0)
+		}
+	}
+	if strings.HasPrefix(operand.function, "end") {
+		t = t.Add(-time.Millisecond)
+	}
+	return t.Format(jiraTimeFormat), nil
+}
+
+var jqlRelativeRegexp = regexp.MustCompile(`^([-+]?)((?:\s*\d+\s*[yMwdhm]?)+)$`)
+var jqlRelativePartRegexp = regexp.MustCompile(`(\d+)\s*([yMwdhm]?)`)
+
+// jqlRelative will return the time offset by a relative duration like "-7d"
+// or "-1w 2d", numbers without a unit are in the given unit
+func jqlRelative(t time.Time, relative string, unit string) (time.Time, error) {
+	match := jqlRelativeRegexp.FindStringSubmatch(strings.TrimSpace(relative))
+	if match == nil {
+		return t, fmt.Errorf("Invalid relative date '%s' in JQL", relative)
+	}
+	sign := 1
+	if match[1] == "-" {
+		sign = -1
+	}
+	for _, part := range jqlRelativePartRegexp.FindAllStringSubmatch(match[2], -1) {
+		n, _ := strconv.Atoi(part[1])
+		n *= sign
+		u := part[2]
+		if u == "" {
+			u = unit
+		}
+		switch u {
+		case "y":
+			t = t.AddDate(n, 0, 0)
+		case "M":
+			t = t.AddDate(0, n, 0)
+		case "w":
+			t = t.AddDate(0, 0, 7*n)
+		case "d":
+			t = t.AddDate(0, 0, n)
+		case "h":
+			t = t.Add(time.Duration(n) * time.Hour)
+		case "m":
+			t = t.Add(time.Duration(n) * time.Minute)
+		}
+	}
+	return t, nil
+}
+
+// parseFieldTime will parse a date or datetime field value
+func parseFieldTime(value string) (time.Time, bool) {
+	if t, err := time.Parse(jiraTimeFormat, value); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// parseDate will parse a date operand, either absolute like "2017/01/31" or
+// "2017-01-31 13:45", or relative to now like "-7d"
+func (env *JQLEnv) parseDate(operand string) (time.Time, error) {
+	if t, ok := parseFieldTime(operand); ok {
+		return t, nil
+	}
+	for _, format := range []string{"2006/01/02 15:04", "2006-01-02 15:04", "2006/01/02"} {
+		if t, err := time.ParseInLocation(format, operand, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return jqlRelative(env.Now, operand, "m")
+}
+
+var jqlKeyRegexp = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9_]*)-(\d+)$`)
+
+// compare will compare the field value with the operand as dates, numbers or
+// issue keys, other values can only be compared for equality
+func (env *JQLEnv) compare(field string, value string, operand string) (int, error) {
+	if t, ok := parseFieldTime(value); ok {
+		o, err := env.parseDate(operand)
+		if err != nil {
+			return 0, err
+		}
+		return compareTimes(t, o), nil
+	}
+	if cmp, ok := compareValues(value, operand); ok {
+		return cmp, nil
+	}
+	return 0, fmt.Errorf("Unable to compare %s value '%s' with '%s'", field, value, operand)
+}
+
+// equal will compare the field value with the operand, dates are compared
+// as times and other values without case
+func (env *JQLEnv) equal(value string, operand string) (bool, error) {
+	if t, ok := parseFieldTime(value); ok {
+		o, err := env.parseDate(operand)
+		if err != nil {
+			return false, nil
+		}
+		return t.Equal(o), nil
+	}
+	if cmp, ok := compareValues(value, operand); ok {
+		return cmp == 0, nil
+	}
+	return strings.EqualFold(value, operand), nil
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareValues will compare numbers and issue keys, false is returned for
+// other values
+func compareValues(a, b string) (int, bool) {
+	if x, err := strconv.ParseFloat(a, 64); err == nil {
+		if y, err := strconv.ParseFloat(b, 64); err == nil {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	if x := jqlKeyRegexp.FindStringSubmatch(a); x != nil {
+		if y := jqlKeyRegexp.FindStringSubmatch(b); y != nil {
+			if cmp := strings.Compare(strings.ToUpper(x[1]), strings.ToUpper(y[1])); cmp != 0 {
+				return cmp, true
+			}
+			xn, _ := strconv.Atoi(x[2])
+			yn, _ := strconv.Atoi(y[2])
+			return compareInts(xn, yn), true
+		}
+	}
+	return 0, false
+}
+
+// sortCompare will compare the first values of the field of two issues
+func (env *JQLEnv) sortCompare(field string, a []string, b []string) int {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+	if strings.EqualFold(field, "priority") {
+		// priorities are ordered by their id, with the highest priority
+		// first, so the lowest priority sorts first ascending like jira
+		x, _ := strconv.Atoi(a[len(a)-1])
+		y, _ := strconv.Atoi(b[len(b)-1])
+		return compareInts(y, x)
+	}
+	if x, ok := parseFieldTime(a[0]); ok {
+		if y, ok := parseFieldTime(b[0]); ok {
+			return compareTimes(x, y)
+		}
+	}
+	if cmp, ok := compareValues(a[0], b[0]); ok {
+		return cmp
+	}
+	return strings.Compare(strings.ToLower(a[0]), strings.ToLower(b[0]))
+}
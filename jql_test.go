@@ -0,0 +1,175 @@
+package jira
+
+import (
+	"testing"
+	"time"
+)
+
+func testJQLEnv() *JQLEnv {
+	return &JQLEnv{
+		User: "gojira",
+		Now:  time.Date(2017, 2, 1, 12, 0, 0, 0, time.UTC),
+		Resolve: func(name string) string {
+			if name == "Story Points" {
+				return "customfield_10002"
+			}
+			return ""
+		},
+	}
+}
+
+func testJQLIssue(key string, resolution interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"key": key,
+		"fields": map[string]interface{}{
+			"summary":    "Disk full on db1",
+			"project":    map[string]interface{}{"key": "GOJIRA", "name": "Go Jira"},
+			"status":     map[string]interface{}{"name": "In Progress", "statusCategory": map[string]interface{}{"key": "indeterminate", "name": "In Progress"}},
+			"resolution": resolution,
+			"assignee":   map[string]interface{}{"name": "gojira", "displayName": "Gojira"},
+			"labels":     []interface{}{"alert", "db"},
+			"priority":   map[string]interface{}{"name": "High", "id": "2"},
+			"updated":    "2017-01-31T10:00:00.000+0000",
+			"comment": map[string]interface{}{
+				"comments": []interface{}{map[string]interface{}{"body": "rebooted the host"}},
+			},
+			"customfield_10002": 3.0,
+		},
+	}
+}
+
+func TestJQLMatch(t *testing.T) {
+	env := testJQLEnv()
+	unresolved := testJQLIssue("GOJIRA-12", nil)
+	fixed := testJQLIssue("GOJIRA-12", map[string]interface{}{"name": "Fixed"})
+	for query, expected := range map[string][2]bool{
+		// {unresolved, fixed}
+		"resolution = unresolved":                              {true, false},
+		"resolution = Unresolved":                              {true, false},
+		"resolution != unresolved":                             {false, true},
+		"resolution in (unresolved, Fixed)":                    {true, true},
+		"resolution not in (unresolved)":                       {false, true},
+		"resolution is EMPTY":                                  {true, false},
+		"resolution is not empty":                              {false, true},
+		"resolution = Fixed":                                   {false, true},
+		"project = gojira AND resolution = unresolved":         {true, false},
+		"project = OTHER OR resolution = Fixed":                {false, true},
+		"NOT resolution = Fixed":                               {true, false},
+		"project = OTHER AND (labels = db OR priority = High)": {false, false},
+		"project = GOJIRA AND (labels = db OR priority = Low)": {true, true},
+		"labels in (alert, other) and labels not in (x)":       {true, true},
+		"status = \"In Progress\"":                             {true, true},
+		"statusCategory = indeterminate":                       {true, true},
+		"assignee = currentUser()":                             {true, true},
+		"assignee = Gojira":                                    {true, true},
+		"reporter is EMPTY":                                    {true, true},
+		"reporter = gojira":                                    {false, false},
+		"reporter != gojira":                                   {false, false},
+		"summary ~ \"disk full\"":                              {true, true},
+		"summary !~ memory":                                    {true, true},
+		"comment ~ reboot*":                                    {true, true},
+		"text ~ rebooted":                                      {true, true},
+		"key = GOJIRA-12":                                      {true, true},
+		"key > GOJIRA-9 and key < GOJIRA-100":                  {true, true},
+		"updated >= -2d":                                       {true, true},
+		"updated < startOfDay(-1)":                             {false, false},
+		"updated >= \"2017/01/31\"":                            {true, true},
+		"\"Story Points\" > 2":                                 {true, true},
+		"cf[10002] <= 2.5":                                     {false, false},
+	} {
+		jql, err := ParseJQL(query)
+		if err != nil {
+			t.Errorf("%s: %s", query, err)
+			continue
+		}
+		for i, issue := range []map[string]interface{}{unresolved, fixed} {
+			ok, err := jql.Match(env, issue)
+			if err != nil {
+				t.Errorf("%s: %s", query, err)
+			} else if ok != expected[i] {
+				t.Errorf("%s: expected %t for %v, got %t", query, expected[i], issue["fields"].(map[string]interface{})["resolution"], ok)
+			}
+		}
+	}
+}
+
+func TestJQLMatchBuilder(t *testing.T) {
+	// the clauses generated for the list options
+	query := NewJQLBuilder().
+		Where(JQLEquals("resolution", JQLUnresolved)).
+		Where(JQLEquals("project", JQLString("GOJIRA"))).
+		String()
+	jql, err := ParseJQL(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := jql.Match(testJQLEnv(), testJQLIssue("GOJIRA-1", nil)); err != nil || !ok {
+		t.Errorf("expected %s to match, got %t %v", query, ok, err)
+	}
+}
+
+func TestParseJQLErrors(t *testing.T) {
+	for _, query := range []string{
+		"project =",
+		"project GOJIRA",
+		"(project = GOJIRA",
+		"project = GOJIRA)",
+		"labels in (a, b",
+		"labels not (a)",
+		"assignee is gojira",
+		"status was Done",
+		"status changed",
+		"assignee = membersOf(devs)",
+		"summary ~ \"unterminated",
+	} {
+		if _, err := ParseJQL(query); err == nil {
+			t.Errorf("expected an error for %s", query)
+		}
+	}
+}
+
+func TestJQLSort(t *testing.T) {
+	issue := func(key string, priority string, updated interface{}) interface{} {
+		return map[string]interface{}{
+			"key": key,
+			"fields": map[string]interface{}{
+				"priority": map[string]interface{}{"name": priority, "id": map[string]string{"High": "2", "Low": "4"}[priority]},
+				"updated":  updated,
+			},
+		}
+	}
+	keys := func(issues []interface{}) []string {
+		keys := []string{}
+		for _, i := range issues {
+			keys = append(keys, i.(map[string]interface{})["key"].(string))
+		}
+		return keys
+	}
+	issues := []interface{}{
+		issue("GOJIRA-10", "High", "2017-01-02T00:00:00.000+0000"),
+		issue("GOJIRA-9", "Low", nil),
+		issue("GOJIRA-100", "Low", "2017-01-03T00:00:00.000+0000"),
+	}
+	for query, expected := range map[string][]string{
+		"project = GOJIRA":                          {"GOJIRA-9", "GOJIRA-10", "GOJIRA-100"},
+		"project = GOJIRA ORDER BY key DESC":        {"GOJIRA-100", "GOJIRA-10", "GOJIRA-9"},
+		"order by updated":                          {"GOJIRA-10", "GOJIRA-100", "GOJIRA-9"},
+		"order by priority asc, key desc":           {"GOJIRA-100", "GOJIRA-9", "GOJIRA-10"},
+		"order by priority desc, updated desc, key": {"GOJIRA-10", "GOJIRA-9", "GOJIRA-100"},
+	} {
+		jql, err := ParseJQL(query)
+		if err != nil {
+			t.Errorf("%s: %s", query, err)
+			continue
+		}
+		sorted := append([]interface{}{}, issues...)
+		jql.Sort(testJQLEnv(), sorted)
+		got := keys(sorted)
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Errorf("%s: expected %v, got %v", query, expected, got)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package jira
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// JQLValue is a value in a JQL clause, strings are always quoted so they are
+// compared as values and can not change the query
+type JQLValue struct {
+	text string
+}
+
+// JQLClause is a condition of a JQL query, clauses are composed with JQLAnd,
+// JQLOr and JQLNot
+type JQLClause struct {
+	text string
+	// op is the boolean operator joining the parts of the clause, so it is
+	// wrapped in parenthesis when composed with a different operator
+	op string
+}
+
+// JQLBuilder builds a JQL query from clauses, which are ANDed together, and
+// an ORDER BY:
+//
+//	jql := NewJQLBuilder().
+//		Where(JQLEquals("project", JQLString("GOJIRA"))).
+//		Where(JQLIn("status", JQLString("To Do"), JQLString("In Progress"))).
+//		OrderBy("priority", true)
+//	query := jql.String()
+type JQLBuilder struct {
+	where   []JQLClause
+	orderBy []string
+}
+
+// JQLEmpty is the EMPTY value, for clauses like 'assignee is EMPTY'
+var JQLEmpty = JQLValue{"EMPTY"}
+
+// JQLUnresolved is the unresolved keyword for the resolution field
+var JQLUnresolved = JQLValue{"unresolved"}
+
+// the words jira reserves in JQL, fields with these names must be quoted
+var jqlReservedWords = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`a an abort access add after alias all alter and any are as asc audit avg before begin between
+		boolean break by byte catch cf char character check checkpoint collate collation column commit connect continue count create
+		current date decimal declare decrement default defaults define delete delimiter desc difference distinct divide do double drop
+		else empty encoding end equals escape exclusive exec execute exists explain false fetch file field first float for from
+		function go goto grant greater group having identified if immediate in increment index initial inner inout input insert int
+		integer intersect intersection into is isempty isnull join last left less like limit lock long max min minus mode modify
+		modulo more multiply next noaudit not notin nowait null number object of on option or order outer output power previous prior
+		privileges public raise raw remainder rename resource return returns revoke right row rowid rownum rows select session set
+		share size sqrt start strict string subtract sum synonym table then to trans transaction trigger true uid union unique update
+		user validate values view when whenever where while with`) {
+		jqlReservedWords[word] = true
+	}
+}
+
+var jqlIdentifierRegexp = regexp.MustCompile(`^(?:[A-Za-z_][A-Za-z0-9_.]*|(?i:cf)\[\d+\])$`)
+var jqlFunctionRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// jqlOperators are the operators allowed by JQLCompare
+var jqlOperators = map[string]bool{
+	"=": true, "!=": true, "~": true, "!~": true, ">": true, ">=": true, "<": true, "<=": true, "is": true, "is not": true,
+}
+
+// JQLQuote will quote a string for JQL, escaping quotes and backslashes
+func JQLQuote(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	return `"` + strings.Replace(value, `"`, `\"`, -1) + `"`
+}
+
+// JQLField will return the field name for JQL, names that are not plain
+// identifiers (like "Story Points") or are reserved words are quoted
+func JQLField(name string) string {
+	if jqlIdentifierRegexp.MatchString(name) && !jqlReservedWords[strings.ToLower(name)] {
+		return name
+	}
+	return JQLQuote(name)
+}
+
+// JQLString is a string value
+func JQLString(value string) JQLValue {
+	return JQLValue{JQLQuote(value)}
+}
+
+// JQLFunction is a function call value, like currentUser() or
+// startOfDay("-7d")
+func JQLFunction(name string, args ...string) (JQLValue, error) {
+	if !jqlFunctionRegexp.MatchString(name) {
+		return JQLValue{}, fmt.Errorf("Invalid JQL function name '%s'", name)
+	}
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, JQLQuote(arg))
+	}
+	return JQLValue{fmt.Sprintf("%s(%s)", name, strings.Join(quoted, ", "))}, nil
+}
+
+// String will return the value as JQL
+func (v JQLValue) String() string {
+	return v.text
+}
+
+// JQLCompare is a clause comparing the field with the value using one of the
+// JQL operators: =, !=, ~, !~, >, >=, <, <=, is or is not
+func JQLCompare(field string, operator string, value JQLValue) (JQLClause, error) {
+	operator = strings.ToLower(operator)
+	if !jqlOperators[operator] {
+		return JQLClause{}, fmt.Errorf("Invalid JQL operator '%s'", operator)
+	}
+	return JQLClause{text: fmt.Sprintf("%s %s %s", JQLField(field), operator, value)}, nil
+}
+
+// JQLEquals is a clause matching the field equal to the value
+func JQLEquals(field string, value JQLValue) JQLClause {
+	return JQLClause{text: fmt.Sprintf("%s = %s", JQLField(field), value)}
+}
+
+// JQLIn is a clause matching the field equal to any of the values
+func JQLIn(field string, values ...JQLValue) JQLClause {
+	return jqlList(field, "in", values)
+}
+
+// JQLNotIn is a clause matching the field equal to none of the values
+func JQLNotIn(field string, values ...JQLValue) JQLClause {
+	return jqlList(field, "not in", values)
+}
+
+// JQLInFunction is a clause matching the field equal to any of the values
+// returned by the function, like 'sprint in openSprints()'
+func JQLInFunction(field string, name string, args ...string) (JQLClause, error) {
+	function, err := JQLFunction(name, args...)
+	if err != nil {
+		return JQLClause{}, err
+	}
+	return JQLClause{text: fmt.Sprintf("%s in %s", JQLField(field), function)}, nil
+}
+
+func jqlList(field string, operator string, values []JQLValue) JQLClause {
+	items := make([]string, 0, len(values))
+	for _, value := range values {
+		items = append(items, value.String())
+	}
+	return JQLClause{text: fmt.Sprintf("%s %s (%s)", JQLField(field), operator, strings.Join(items, ", "))}
+}
+
+// JQLRaw is a clause of JQL written by hand, like the 'query' option.  It
+// is not validated, but it is wrapped in parenthesis when composed so it
+// can not change the meaning of the other clauses.
+func JQLRaw(query string) JQLClause {
+	return JQLClause{text: query, op: "raw"}
+}
+
+// JQLAnd is a clause matching all of the clauses
+func JQLAnd(clauses ...JQLClause) JQLClause {
+	return jqlJoin("AND", clauses)
+}
+
+// JQLOr is a clause matching any of the clauses
+func JQLOr(clauses ...JQLClause) JQLClause {
+	return jqlJoin("OR", clauses)
+}
+
+// JQLNot is a clause matching when the clause does not
+func JQLNot(clause JQLClause) JQLClause {
+	return JQLClause{text: "NOT " + clause.group("NOT"), op: "NOT"}
+}
+
+func jqlJoin(op string, clauses []JQLClause) JQLClause {
+	if len(clauses) == 1 {
+		return clauses[0]
+	}
+	parts := make([]string, 0, len(clauses))
+	for _, clause := range clauses {
+		parts = append(parts, clause.group(op))
+	}
+	return JQLClause{text: strings.Join(parts, " "+op+" "), op: op}
+}
+
+// group will return the clause to be composed with the operator, adding
+// parenthesis when needed
+func (c JQLClause) group(op string) string {
+	if c.op == "" || c.op == op && op != "NOT" {
+		return c.text
+	}
+	return "(" + c.text + ")"
+}
+
+// String will return the clause as JQL
+func (c JQLClause) String() string {
+	return c.text
+}
+
+// NewJQLBuilder will return an empty query
+func NewJQLBuilder() *JQLBuilder {
+	return &JQLBuilder{}
+}
+
+// Where will add the clause to the query, all the clauses must match
+func (b *JQLBuilder) Where(clause JQLClause) *JQLBuilder {
+	if clause.text != "" {
+		b.where = append(b.where, clause)
+	}
+	return b
+}
+
+// OrderBy will add the field to the ORDER BY of the query
+func (b *JQLBuilder) OrderBy(field string, desc bool) *JQLBuilder {
+	order := JQLField(field)
+	if desc {
+		order += " DESC"
+	} else {
+		order += " ASC"
+	}
+	b.orderBy = append(b.orderBy, order)
+	return b
+}
+
+// SortBy will add the fields of an ORDER BY expression, like
+// "priority asc, key", to the query.  Anything else is rejected.
+func (b *JQLBuilder) SortBy(sort string) error {
+	query, err := ParseJQL("ORDER BY " + sort)
+	if err != nil || query.where != nil {
+		return fmt.Errorf("Invalid sort order '%s'", sort)
+	}
+	for _, order := range query.orderBy {
+		b.OrderBy(order.field, order.desc)
+	}
+	return nil
+}
+
+// String will return the query as JQL
+func (b *JQLBuilder) String() string {
+	query := JQLAnd(b.where...).text
+	if len(b.orderBy) > 0 {
+		query = strings.TrimSpace(query + " ORDER BY " + strings.Join(b.orderBy, ", "))
+	}
+	return query
+}
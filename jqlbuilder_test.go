@@ -0,0 +1,49 @@
+package jira
+
+import "testing"
+
+func TestJQLCompareOperators(t *testing.T) {
+	clause, err := JQLCompare("Story Points", ">=", JQLString("3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `"Story Points" >= "3"`; clause.String() != expected {
+		t.Errorf("expected %s, got %s", expected, clause)
+	}
+	// lists are only built with JQLIn, JQLNotIn and JQLInFunction
+	for _, operator := range []string{"in", "NOT IN", "was", "= 1 OR x"} {
+		if clause, err := JQLCompare("labels", operator, JQLString("a")); err == nil {
+			t.Errorf("expected an error for %q, got %s", operator, clause)
+		}
+	}
+}
+
+func TestJQLInFunction(t *testing.T) {
+	clause, err := JQLInFunction("sprint", "openSprints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "sprint in openSprints()"; clause.String() != expected {
+		t.Errorf("expected %s, got %s", expected, clause)
+	}
+	clause, err = JQLInFunction("assignee", "membersOf", `jira "devs"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `assignee in membersOf("jira \"devs\"")`; clause.String() != expected {
+		t.Errorf("expected %s, got %s", expected, clause)
+	}
+	if _, err := JQLInFunction("sprint", "openSprints() OR x"); err == nil {
+		t.Error("expected an error for an invalid function name")
+	}
+}
+
+func TestJQLSprints(t *testing.T) {
+	clause, err := jqlSprints([]string{"current", "Sprint 1", "closed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `sprint in openSprints() OR sprint in closedSprints() OR sprint in ("Sprint 1")`; clause.String() != expected {
+		t.Errorf("expected %s, got %s", expected, clause)
+	}
+}
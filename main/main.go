@@ -55,20 +55,43 @@ func main() {
 		}
 		output := fmt.Sprintf(`
 Usage:
-  jira (ls|list) <Query Options> 
-  jira view ISSUE
+  jira (ls|list) <Query Options> [--output FORMAT] [--columns COLUMNS]
+  jira view ISSUE [--output FORMAT] [--columns COLUMNS] [-x remotelinks]
+  jira sync [<Query Options>] [--full] [--all]
+  jira history ISSUE [--field FIELD] [--author USER] [--since DURATION|DATE]
   jira worklog ISSUE
   jira add worklog ISSUE <Worklog Options>
-  jira edit [--noedit] <Edit Options> [ISSUE | <Query Options>]
-  jira create [--noedit] [-p PROJECT] <Create Options>
-  jira subtask ISSUE [--noedit] <Create Options>
+  jira worklog edit ISSUE ID <Worklog Options>
+  jira worklog rm ISSUE ID [--adjust-estimate=MODE]
+  jira attach ISSUE FILE...
+  jira attachments ISSUE
+  jira attachment get ID [-O PATH]
+  jira attachment rm ID
+  jira edit [--noedit] [--file PATH] <Edit Options> [ISSUE | <Query Options>]
+  jira create [--noedit] [--file PATH] [-p PROJECT] <Create Options> [--attach FILE]...
+  jira subtask ISSUE [--noedit] [--file PATH] <Create Options> [--attach FILE]...
+  jira move ISSUE [--noedit] [-p PROJECT] [-i ISSUETYPE]
+  jira plan FILE
+  jira apply FILE [--yes]
+  jira import FILE [-p PROJECT] [-i ISSUETYPE] [--concurrency N] [--failures PATH]
+  jira backup -p PROJECT DIR [--with-attachments] [--full]
+  jira restore DIR -p PROJECT
+  jira mirror --to PROFILE [--from PROFILE] [--mapping FILE] -q JQL
+  jira delete [--subtasks] [--yes] (ISSUE... | -q JQL)
+  jira clone ISSUE [--noedit] [-p PROJECT] [-i ISSUETYPE] [--with-subtasks] [--with-links]
   jira DUPLICATE dups ISSUE
   jira BLOCKER blocks ISSUE
   jira issuelink OUTWARDISSUE ISSUELINKTYPE INWARDISSUE
+  jira links ISSUE
+  jira unlink ISSUE OTHERISSUE [ISSUELINKTYPE]
+  jira remotelink add ISSUE URL [--title TITLE] [--icon URL]
+  jira remotelink rm ISSUE ID
+  jira remotelinks ISSUE
   jira vote ISSUE [--down]
   jira rank ISSUE (after|before) ISSUE
   jira watch ISSUE [-w WATCHER] [--remove]
-  jira (trans|transition) TRANSITION ISSUE [--noedit] <Edit Options>
+  jira (trans|transition) TRANSITION ISSUE [--noedit] [--file PATH] <Edit Options>
+  jira move-to ISSUE STATUS [-m COMMENT]
   jira ack ISSUE [--edit] <Edit Options>
   jira close ISSUE [--edit] <Edit Options>
   jira resolve ISSUE [--edit] <Edit Options>
@@ -79,7 +102,10 @@ Usage:
   jira backlog ISSUE [--edit] <Edit Options>
   jira done ISSUE [--edit] <Edit Options>
   jira prog|progress|in-progress [--edit] <Edit Options>
-  jira comment ISSUE [--noedit] <Edit Options>
+  jira comment ISSUE [--noedit] <Edit Options> [--attach FILE]... [--visibility TYPE:NAME]
+  jira comment edit ISSUE ID [--noedit] <Edit Options> [--visibility TYPE:NAME]
+  jira comment rm ISSUE ID
+  jira comments ISSUE
   jira (set,add,remove) labels ISSUE [LABEL] ...
   jira take ISSUE
   jira (assign|give) ISSUE [ASSIGNEE|--default]
@@ -93,6 +119,7 @@ Usage:
   jira issuetypes [-p PROJECT] 
   jira createmeta [-p PROJECT] [-i ISSUETYPE] 
   jira transitions ISSUE
+  jira workflow discover -p PROJECT
   jira export-templates [-d DIR] [-t template]
   jira (b|browse) ISSUE
   jira login
@@ -104,6 +131,8 @@ General Options:
   -b --browse         Open your browser to the Jira issue
   -e --endpoint=URI   URI to use for jira
   -k --insecure       disable TLS certificate verification
+  --offline           Read issues from the offline cache, see "jira sync"
+  --profile=NAME      Use the options of NAME from the "profiles" config
   -h --help           Show this usage
   -t --template=FILE  Template file to use for output/editing
   -u --user=USER      Username to use for authenticaion (default: %s)
@@ -117,6 +146,7 @@ Query Options:
   -f --queryfields=FIELDS   Fields that are used in "list" template: (default: %s)
   -i --issuetype=ISSUETYPE  The Issue Type
   -l --limit=VAL            Maximum number of results to return in query (default: %d)
+                            with --output 0 returns all the results
   --start=START             Start parameter for pagination
   -p --project=PROJECT      Project to Search for
   -q --query=JQL            Jira Query Language expression for the search
@@ -124,22 +154,82 @@ Query Options:
   -s --sort=ORDER           For list operations, sort issues (default: %s)
   -w --watcher=USER         Watcher to add to issue (default: %s)
                             or Watcher to search for
+  --created-since=DURATION  Issues created since a duration ago (eg 7d) or a date
+  --epic=EPIC               Issues in the epic
+  --fix-version=VERSION     Issues to be fixed in the version
+  --include-resolved        Include resolved issues
+  --label=LABEL             Issues with the label
+  --mine                    Issues assigned to you
+  --priority=PRIORITY       Issues with the priority
+  --sprint=SPRINT           Issues in the sprint, or "current", "future" or "closed"
+  --status=STATUS           Issues in the status
+  --status-category=CAT     Issues in the status category (eg "To Do", "In Progress", Done)
+  --text=TEXT               Issues with the text in the summary, description or comments
+  --updated-since=DURATION  Issues updated since a duration ago (eg 7d) or a date
+                            The epic, fix-version, label, priority, sprint, status,
+                            status-category and text options may be repeated or
+                            comma separated, issues match any of the values except
+                            for text where all of them must match
 
 Edit Options:
-  -m --comment=COMMENT      Comment message for transition
-  -o --override=KEY=VAL     Set custom key/value pairs
+  -m --comment=COMMENT      Comment message for transition, use @FILE to read
+                            the comment from a file or "-" to read from stdin
+  -o --override=KEY=VAL     Set custom key/value pairs, or any field by id or
+                            display name, KEY+=VAL and KEY-=VAL add to and
+                            remove from a field
+  --file=PATH               Submit the YAML or JSON issue document in PATH
+                            instead of the template, "-" to read from stdin
 
 Create Options:
   -i --issuetype=ISSUETYPE  Jira Issue Type (default: Bug)
   -m --comment=COMMENT      Comment message for transition
-  -o --override=KEY=VAL     Set custom key/value pairs
+  -o --override=KEY=VAL     Set custom key/value pairs, or any field by id or
+                            display name, KEY+=VAL and KEY-=VAL add to and
+                            remove from a field
+  --file=PATH               Submit the YAML or JSON issue document in PATH
+                            instead of the template, "-" to read from stdin
 
 Worklog Options:
-  -T --time-spent=TIMESPENT Time spent working on issue
+  -T --time-spent=TIMESPENT Time spent working on issue (eg "1h 30m")
   -m --comment=COMMENT      Comment message for worklog
+  --adjust-estimate=MODE    How to update the remaining estimate: new, leave, manual, auto
+  --new-estimate=DURATION   Remaining estimate to set with --adjust-estimate=new
+  --reduce-by=DURATION      Amount to reduce the estimate with --adjust-estimate=manual (not for worklog edit)
+  --increase-by=DURATION    Amount to increase the estimate with --adjust-estimate=manual when removing
 
 Command Options:
+  --all                     Sync every query in the offline cache again
+  --attach=FILE             File to attach to the issue, may be repeated
+  --author=USER             Only show history changes made by USER
+  --columns=COLUMNS         Comma separated fields to output, by id or name,
+                            with a path for properties (eg status.name)
+  --concurrency=N           Number of issues to import at a time (default: 4)
   -d --directory=DIR        Directory to export templates to (default: %s)
+  --failures=PATH           File to write the rows that failed to import to
+                            (default: FILE.failed.yml)
+  --field=FIELDS            Only show history changes to the comma separated FIELDS
+  --from=PROFILE            Profile to mirror issues from (default: the current options)
+  --full                    Back up or sync every issue again instead of only those
+                            updated since the last time
+  --icon=URL                Icon url for the remote link
+  --mapping=FILE            YAML file mapping the project, issue types, fields,
+                            users and statuses for mirror
+  --output=FORMAT           Output csv, tsv, jsonl, yaml or markdown instead
+                            of using the template
+  -O --outfile=PATH         Path to write downloaded attachment to, "-" for stdout
+                            (not -o, which is the override option)
+  --since=DURATION|DATE     Only show history changes since a duration ago (eg 7d) or a date (eg 2017-01-31)
+  --subtasks                Delete the subtasks of the issues as well
+  --title=TITLE             Title for the remote link (default: the url)
+  --to=PROFILE              Profile to mirror issues to
+  --visibility=TYPE:NAME    Restrict comment visibility to a role or group (eg role:Developers, group:eng)
+  --with-attachments        Download the attachments of the issues in the backup
+  --with-links              Recreate the issue links of the source issue on the clone
+  --with-subtasks           Recreate the subtasks of the source issue on the clone
+  -x --expand=EXPAND        Comma separated expansions to request with the issue,
+                            "remotelinks" includes the remote links in view
+  -y --yes                  Do not prompt for confirmation before deleting issues
+                            or applying changes
 `, user, defaultQueryFields, defaultMaxResults, defaultSort, user, fmt.Sprintf("%s/.jira.d/templates", home))
 		printer(output)
 	}
@@ -148,15 +238,31 @@ Command Options:
 		"list":             "list",
 		"ls":               "list",
 		"view":             "view",
+		"history":          "history",
 		"edit":             "edit",
 		"create":           "create",
 		"subtask":          "subtask",
+		"clone":            "clone",
+		"delete":           "delete",
+		"import":           "import",
+		"backup":           "backup",
+		"restore":          "restore",
+		"mirror":           "mirror",
+		"sync":             "sync",
+		"plan":             "plan",
+		"apply":            "apply",
+		"move":             "move",
 		"dups":             "dups",
 		"blocks":           "blocks",
 		"issuelink":        "issuelink",
+		"links":            "links",
+		"unlink":           "unlink",
+		"remotelink":       "remotelink",
+		"remotelinks":      "remotelinks",
 		"watch":            "watch",
 		"trans":            "transition",
 		"transition":       "transition",
+		"move-to":          "move-to",
 		"ack":              "acknowledge",
 		"acknowledge":      "acknowledge",
 		"close":            "close",
@@ -171,6 +277,7 @@ Command Options:
 		"progress":         "in-progress",
 		"in-progress":      "in-progress",
 		"comment":          "comment",
+		"comments":         "comments",
 		"label":            "labels",
 		"labels":           "labels",
 		"component":        "component",
@@ -185,6 +292,7 @@ Command Options:
 		"issuetypes":       "issuetypes",
 		"createmeta":       "createmeta",
 		"transitions":      "transitions",
+		"workflow":         "workflow",
 		"export-templates": "export-templates",
 		"browse":           "browse",
 		"login":            "login",
@@ -196,6 +304,9 @@ Command Options:
 		"worklog":          "worklog",
 		"addworklog":       "addworklog",
 		"unassign":         "unassign",
+		"attach":           "attach",
+		"attachments":      "attachments",
+		"attachment":       "attachment",
 	}
 
 	defaults := map[string]interface{}{
@@ -208,6 +319,13 @@ Command Options:
 		"quiet":       false,
 	}
 	opts := make(map[string]interface{})
+	attachments := []string{}
+	// query options that may be repeated
+	queryLists := map[string]*[]string{}
+	for _, name := range []string{"status", "status-category", "label", "priority", "text", "sprint", "fix-version", "epic"} {
+		queryLists[name] = &[]string{}
+	}
+	overrideArgs := []string{}
 
 	setopt := func(name string, value interface{}) {
 		opts[name] = value
@@ -237,23 +355,64 @@ Command Options:
 		"w|watcher=s":           setopt,
 		"remove":                setopt,
 		"r|reporter=s":          setopt,
+		"status=s@":             queryLists["status"],
+		"status-category=s@":    queryLists["status-category"],
+		"label=s@":              queryLists["label"],
+		"priority=s@":           queryLists["priority"],
+		"created-since=s":       setopt,
+		"updated-since=s":       setopt,
+		"text=s@":               queryLists["text"],
+		"sprint=s@":             queryLists["sprint"],
+		"fix-version=s@":        queryLists["fix-version"],
+		"epic=s@":               queryLists["epic"],
+		"mine":                  setopt,
+		"include-resolved":      setopt,
 		"f|queryfields=s":       setopt,
 		"x|expand=s":            setopt,
 		"s|sort=s":              setopt,
 		"l|limit|max_results=i": setopt,
 		"start|start_at=i":      setopt,
-		"o|override=s%":         &opts,
+		"o|override=s@":         &overrideArgs,
 		"noedit":                setopt,
+		"file=s":                setopt,
+		"concurrency=i":         setopt,
+		"output=s":              setopt,
+		"columns=s":             setopt,
+		"failures=s":            setopt,
+		"full":                  setopt,
+		"with-attachments":      setopt,
+		"from=s":                setopt,
+		"to=s":                  setopt,
+		"mapping=s":             setopt,
+		"profile=s":             setopt,
+		"offline":               setopt,
+		"all":                   setopt,
 		"edit":                  setopt,
 		"m|comment=s":           setopt,
 		"d|dir|directory=s":     setopt,
 		"M|method=s":            setopt,
 		"S|saveFile=s":          setopt,
 		"T|time-spent=s":        setopt,
+		"adjust-estimate=s":     setopt,
+		"new-estimate=s":        setopt,
+		"reduce-by=s":           setopt,
+		"increase-by=s":         setopt,
 		"Q|quiet":               setopt,
 		"unixproxy":             setopt,
 		"down":                  setopt,
 		"default":               setopt,
+		"attach=s@":             &attachments,
+		"O|outfile=s":           setopt,
+		"visibility=s":          setopt,
+		"field=s":               setopt,
+		"author=s":              setopt,
+		"since=s":               setopt,
+		"title=s":               setopt,
+		"icon=s":                setopt,
+		"with-subtasks":         setopt,
+		"with-links":            setopt,
+		"subtasks":              setopt,
+		"y|yes":                 setopt,
 	})
 
 	if err := op.ProcessAll(os.Args[1:]); err != nil {
@@ -261,6 +420,39 @@ Command Options:
 		usage(false)
 	}
 	args := op.Args
+	overrides := make(map[string]interface{})
+	for _, arg := range overrideArgs {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			log.Errorf("Invalid override %q, expected KEY=VAL", arg)
+			usage(false)
+		}
+		key, value := parts[0], parts[1]
+		// KEY+=VAL and KEY-=VAL may be repeated to add or remove several
+		// values, a repeated KEY=VAL keeps the last one
+		if strings.HasSuffix(key, "+") || strings.HasSuffix(key, "-") {
+			values, _ := overrides[key].([]interface{})
+			overrides[key] = append(values, value)
+		} else {
+			overrides[key] = value
+		}
+	}
+	if len(overrides) > 0 {
+		// overrides are template variables, and also set fields
+		// when they name one
+		for key, value := range overrides {
+			opts[key] = value
+		}
+		opts["override"] = overrides
+	}
+	if len(attachments) > 0 {
+		opts["attach"] = attachments
+	}
+	for name, values := range queryLists {
+		if len(*values) > 0 {
+			opts[name] = *values
+		}
+	}
 
 	var command string
 	if len(args) > 0 {
@@ -283,7 +475,24 @@ Command Options:
 	}
 
 	os.Setenv("JIRA_OPERATION", command)
+	// options given on the command line take precedence over a profile
+	cmdlineOpts := make(map[string]interface{})
+	for k, v := range opts {
+		cmdlineOpts[k] = v
+	}
 	loadConfigs(opts)
+	if profile, ok := opts["profile"].(string); ok {
+		profileOpts, err := jira.ProfileOptions(opts, profile)
+		if err != nil {
+			os.Exit(1)
+		}
+		for k, v := range profileOpts {
+			opts[k] = v
+		}
+		for k, v := range cmdlineOpts {
+			opts[k] = v
+		}
+	}
 
 	// check to see if it was set in the configs:
 	if value, ok := opts["command"].(string); ok {
@@ -311,7 +520,17 @@ Command Options:
 		os.Exit(1)
 	}
 
+	if comment, ok := opts["comment"].(string); ok {
+		content, err := readContent(comment)
+		if err != nil {
+			log.Errorf("%s", err)
+			os.Exit(1)
+		}
+		opts["comment"] = content
+	}
+
 	c := jira.New(opts)
+	c.SetCommandLineOptions(cmdlineOpts)
 
 	log.Debugf("opts: %s", opts)
 
@@ -321,6 +540,13 @@ Command Options:
 			if val, ok := opts["noedit"].(bool); ok && val {
 				log.Debugf("Setting edit = false")
 				opts["edit"] = false
+			} else if _, ok := opts["file"].(string); ok {
+				// prepared documents are submitted as is unless
+				// --edit was given
+				if _, ok := opts["edit"].(bool); !ok {
+					log.Debugf("Setting edit = false")
+					opts["edit"] = false
+				}
 			} else {
 				log.Debugf("Setting edit = true")
 				opts["edit"] = true
@@ -345,6 +571,30 @@ Command Options:
 	case "issuelink":
 		requireArgs(3)
 		err = c.CmdIssueLink(args[0], args[1], args[2])
+	case "links":
+		requireArgs(1)
+		err = c.CmdLinks(args[0])
+	case "unlink":
+		requireArgs(2)
+		linkType := ""
+		if len(args) > 2 {
+			linkType = strings.Join(args[2:], " ")
+		}
+		err = c.CmdUnlink(args[0], args[1], linkType)
+	case "remotelinks":
+		requireArgs(1)
+		err = c.CmdRemoteLinks(args[0])
+	case "remotelink":
+		requireArgs(3)
+		switch args[0] {
+		case "add":
+			err = c.CmdRemoteLinkAdd(args[1], args[2])
+		case "rm", "remove", "delete":
+			err = c.CmdRemoteLinkRemove(args[1], args[2])
+		default:
+			log.Errorf("Unknown remotelink action %s", args[0])
+			usage(false)
+		}
 	case "login":
 		err = c.CmdLogin()
 	case "logout":
@@ -392,6 +642,57 @@ Command Options:
 	case "subtask":
 		setEditing(true)
 		err = c.CmdSubtask(args[0])
+	case "clone":
+		requireArgs(1)
+		setEditing(true)
+		err = c.CmdClone(args[0])
+	case "move":
+		requireArgs(1)
+		setEditing(false)
+		err = c.CmdMove(args[0])
+	case "import":
+		requireArgs(1)
+		err = c.CmdImport(args[0])
+	case "backup":
+		requireArgs(1)
+		err = c.CmdBackup(c.GetOptString("project", ""), args[0])
+	case "restore":
+		requireArgs(1)
+		err = c.CmdRestore(args[0])
+	case "sync":
+		err = c.CmdSync()
+	case "mirror":
+		err = c.CmdMirror()
+	case "plan", "apply":
+		// the desired state file can be given with --file as well
+		file := c.GetOptString("file", "")
+		delete(opts, "file")
+		if len(args) > 0 {
+			file = args[0]
+		}
+		if file == "" {
+			requireArgs(1)
+		}
+		if command == "plan" {
+			err = c.CmdPlan(file)
+		} else {
+			err = c.CmdApply(file)
+		}
+	case "delete":
+		if len(args) > 0 {
+			err = c.CmdDelete(args...)
+		} else {
+			err = c.CmdDeleteQuery()
+		}
+	case "workflow":
+		requireArgs(1)
+		switch args[0] {
+		case "discover":
+			err = c.CmdWorkflowDiscover(c.GetOptString("project", ""))
+		default:
+			log.Errorf("Unknown workflow action %s", args[0])
+			usage(false)
+		}
 	case "transitions":
 		requireArgs(1)
 		err = c.CmdTransitions(args[0])
@@ -402,23 +703,7 @@ Command Options:
 		setEditing(true)
 		requireArgs(2)
 		if err = c.CmdDups(args[0], args[1]); err == nil {
-			opts["resolution"] = "Duplicate"
-			trans, err := c.ValidTransitions(args[0])
-			if err == nil {
-				if trans.Find("close") != nil {
-					err = c.CmdTransition(args[0], "close")
-				} else if trans.Find("done") != nil {
-					// for now just assume if there is no "close", then
-					// there is a "done" state
-					err = c.CmdTransition(args[0], "done")
-				} else if trans.Find("start") != nil {
-					err = c.CmdTransition(args[0], "start")
-					if err == nil {
-						err = c.CmdTransition(args[0], "stop")
-					}
-				}
-			}
-
+			err = c.CmdCloseDuplicate(args[0])
 		}
 	case "watch":
 		requireArgs(1)
@@ -429,6 +714,10 @@ Command Options:
 		requireArgs(2)
 		setEditing(true)
 		err = c.CmdTransition(args[1], args[0])
+	case "move-to":
+		requireArgs(2)
+		setEditing(false)
+		err = c.CmdMoveTo(args[0], args[1])
 	case "close":
 		requireArgs(1)
 		setEditing(false)
@@ -456,23 +745,35 @@ Command Options:
 	case "todo":
 		requireArgs(1)
 		setEditing(false)
-		err = c.CmdTransition(args[0], "To Do")
+		err = c.CmdTransition(args[0], "todo")
 	case "backlog":
 		requireArgs(1)
 		setEditing(false)
-		err = c.CmdTransition(args[0], "Backlog")
+		err = c.CmdTransition(args[0], "backlog")
 	case "done":
 		requireArgs(1)
 		setEditing(false)
-		err = c.CmdTransition(args[0], "Done")
+		err = c.CmdTransition(args[0], "done")
 	case "in-progress":
 		requireArgs(1)
 		setEditing(false)
-		err = c.CmdTransition(args[0], "Progress")
+		err = c.CmdTransition(args[0], "in-progress")
 	case "comment":
 		requireArgs(1)
 		setEditing(true)
-		err = c.CmdComment(args[0])
+		switch args[0] {
+		case "edit":
+			requireArgs(3)
+			err = c.CmdCommentEdit(args[1], args[2])
+		case "rm", "remove", "delete":
+			requireArgs(3)
+			err = c.CmdCommentRemove(args[1], args[2])
+		default:
+			err = c.CmdComment(args[0])
+		}
+	case "comments":
+		requireArgs(1)
+		err = c.CmdComments(args[0])
 	case "labels":
 		requireArgs(2)
 		action := args[0]
@@ -518,15 +819,43 @@ Command Options:
 	case "view":
 		requireArgs(1)
 		err = c.CmdView(args[0])
+	case "history":
+		requireArgs(1)
+		err = c.CmdHistory(args[0])
 	case "worklog":
-		if len(args) > 0 && args[0] == "add" {
+		requireArgs(1)
+		switch args[0] {
+		case "add":
 			setEditing(true)
 			requireArgs(2)
 			err = c.CmdWorklog(args[0], args[1])
-		} else {
-			requireArgs(1)
+		case "edit":
+			setEditing(true)
+			requireArgs(3)
+			err = c.CmdWorklogEdit(args[1], args[2])
+		case "rm", "remove", "delete":
+			requireArgs(3)
+			err = c.CmdWorklogRemove(args[1], args[2])
+		default:
 			err = c.CmdWorklogs(args[0])
 		}
+	case "attach":
+		requireArgs(2)
+		err = c.CmdAttach(args[0], args[1:])
+	case "attachments":
+		requireArgs(1)
+		err = c.CmdAttachments(args[0])
+	case "attachment":
+		requireArgs(2)
+		switch args[0] {
+		case "get":
+			err = c.CmdAttachmentGet(args[1])
+		case "rm", "remove", "delete":
+			err = c.CmdAttachmentRemove(args[1])
+		default:
+			log.Errorf("Unknown attachment action %s", args[0])
+			usage(false)
+		}
 	case "vote":
 		requireArgs(1)
 		if val, ok := opts["down"]; ok {
@@ -560,6 +889,26 @@ Command Options:
 	os.Exit(0)
 }
 
+// readContent will return the content from a file when the value
+// is "@FILE", or the content from stdin when the value is "-",
+// otherwise the value is returned unmodified
+func readContent(value string) (string, error) {
+	var content []byte
+	var err error
+	if value == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else if strings.HasPrefix(value, "@") {
+		content, err = ioutil.ReadFile(value[1:])
+	} else {
+		return value, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("Failed to read content from %s: %s", value, err)
+	}
+	// drop the trailing newline that files and pipes usually end with
+	return strings.TrimSuffix(string(content), "\n"), nil
+}
+
 func parseYaml(file string, opts map[string]interface{}) {
 	if fh, err := ioutil.ReadFile(file); err == nil {
 		log.Debugf("Found Config file: %s", file)
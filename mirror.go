@@ -0,0 +1,526 @@
+package jira
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/coryb/yaml.v2"
+)
+
+// mirrorProperty is the issue entity property recording the counterparts of
+// a mirrored issue
+const mirrorProperty = "go-jira.mirror"
+
+// mirrorCommentPrefix starts the comments copied by "mirror", they are never
+// copied back
+const mirrorCommentPrefix = "Mirrored from "
+
+// the fields mirrored when the mapping file does not list any
+var defaultMirrorFields = []string{"summary", "description", "priority", "labels"}
+
+// MirrorMapping is the 'mapping' file for "mirror".  The target issues are
+// created in Project (default: the 'project' option of the target profile),
+// the issue types, statuses and users are mapped by name, and the issues
+// types and statuses default to the same name on the target.  Fields maps
+// the source fields to the target fields, by id or display name.  Users
+// maps the source user names to target user names, or to "accountId:ID" for
+// cloud instances, and users without a mapping are not mirrored when there
+// is a users mapping.
+type MirrorMapping struct {
+	Project    string            `json:"project,omitempty" yaml:"project,omitempty"`
+	IssueTypes map[string]string `json:"issuetypes,omitempty" yaml:"issuetypes,omitempty"`
+	Fields     map[string]string `json:"fields,omitempty" yaml:"fields,omitempty"`
+	Users      map[string]string `json:"users,omitempty" yaml:"users,omitempty"`
+	Statuses   map[string]string `json:"statuses,omitempty" yaml:"statuses,omitempty"`
+}
+
+// mirrorLink is the counterpart of an issue on another endpoint, with the
+// pairs of comment ids (this issue's comment to the counterpart's comment)
+// that have been mirrored
+type mirrorLink struct {
+	Key      string            `json:"key"`
+	Comments map[string]string `json:"comments,omitempty"`
+}
+
+type mirror struct {
+	source  *Cli
+	target  *Cli
+	mapping *MirrorMapping
+	project string
+}
+
+// ProfileOptions will return the options with the options of the named
+// profile from the 'profiles' option applied, the profile options take
+// precedence.  This allows a config.yml to hold the settings for several
+// jira instances:
+//
+//	profiles:
+//	  vendor:
+//	    endpoint: https://vendor.atlassian.net
+//	    user: me@example.com
+func ProfileOptions(opts map[string]interface{}, name string) (map[string]interface{}, error) {
+	fixed, err := yamlFixup(opts["profiles"])
+	if err != nil {
+		return nil, err
+	}
+	profiles, _ := fixed.(map[string]interface{})
+	profile, ok := profiles[name].(map[string]interface{})
+	if !ok {
+		err := fmt.Errorf("Unknown profile '%s', profiles are configured with the 'profiles' option", name)
+		log.Errorf("%s", err)
+		return nil, err
+	}
+	merged := map[string]interface{}{}
+	for k, v := range opts {
+		merged[k] = v
+	}
+	for k, v := range profile {
+		merged[k] = v
+	}
+	return merged, nil
+}
+
+// profileCli will return a Cli for another jira instance, the project and
+// issuetype options belong to the current instance so they are not carried
+// over
+func (c *Cli) profileCli(name string) (*Cli, error) {
+	opts := map[string]interface{}{}
+	for k, v := range c.opts {
+		if k != "project" && k != "issuetype" {
+			opts[k] = v
+		}
+	}
+	opts, err := ProfileOptions(opts, name)
+	if err != nil {
+		return nil, err
+	}
+	return New(opts), nil
+}
+
+func (c *Cli) mirrorMapping() (*MirrorMapping, error) {
+	mapping := &MirrorMapping{}
+	file := c.getOptString("mapping", "")
+	if file == "" {
+		return mapping, nil
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		err = fmt.Errorf("Failed to read %s: %s", file, err)
+		log.Errorf("%s", err)
+		return nil, err
+	}
+	if err := yaml.Unmarshal(content, mapping); err != nil {
+		err = fmt.Errorf("Failed to parse %s: %s", file, err)
+		log.Errorf("%s", err)
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// CmdMirror will create or update a counterpart on the 'to' profile for each
+// issue matching the 'query' option on the 'from' profile (default: the
+// current options).  The mapped fields and the status are updated when they
+// differ, and comments added on either side are copied to the other.  The
+// pairing of the issues and comments is stored in the "go-jira.mirror"
+// entity property of both issues, so later runs, in either direction, update
+// the same counterparts.
+func (c *Cli) CmdMirror() error {
+	log.Debugf("mirror called")
+	to := c.getOptString("to", "")
+	if to == "" {
+		err := fmt.Errorf("Missing required 'to' option")
+		log.Errorf("%s", err)
+		return err
+	}
+	query := c.getOptString("query", "")
+	if query == "" {
+		err := fmt.Errorf("Missing required 'query' option")
+		log.Errorf("%s", err)
+		return err
+	}
+
+	m := &mirror{source: c}
+	var err error
+	if from := c.getOptString("from", ""); from != "" {
+		if m.source, err = c.profileCli(from); err != nil {
+			return err
+		}
+	}
+	if m.target, err = c.profileCli(to); err != nil {
+		return err
+	}
+	if m.mapping, err = c.mirrorMapping(); err != nil {
+		return err
+	}
+	m.project = strings.ToUpper(m.mapping.Project)
+	if m.project == "" {
+		m.project = m.target.getOptString("project", "")
+	}
+	if m.project == "" {
+		err := fmt.Errorf("Missing target project, set 'project' in the mapping file or the '%s' profile", to)
+		log.Errorf("%s", err)
+		return err
+	}
+
+	return m.source.searchAll(query, []string{"*all"}, 0, 0, func(issues []interface{}) error {
+		for _, i := range issues {
+			issueData, _ := i.(map[string]interface{})
+			if err := m.mirrorIssue(issueData); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (m *mirror) mirrorIssue(issueData map[string]interface{}) error {
+	source, _ := issueData["key"].(string)
+	links, err := m.source.mirrorLinks(source)
+	if err != nil {
+		return err
+	}
+	fields := m.mirrorFields(issueData)
+
+	link, ok := links[m.target.endpoint.String()]
+	if !ok {
+		issuetype := issueField(issueData, "issuetype", "name")
+		if mapped, ok := m.mapping.IssueTypes[issuetype]; ok {
+			issuetype = mapped
+		}
+		metaData, err := m.target.createIssueMetaData(m.project, issuetype)
+		if err != nil {
+			return err
+		}
+		meta, _ := metaData.(map[string]interface{})
+		values, dropped := mapFields(fields, meta)
+		if len(dropped) > 0 {
+			log.Warningf("%s fields not allowed for %s %s, skipping: %s", source, m.project, issuetype, strings.Join(dropped, ", "))
+		}
+		values["project"] = map[string]interface{}{"key": m.project}
+		values["issuetype"] = map[string]interface{}{"name": issuetype}
+		json, err := jsonEncode(map[string]interface{}{"fields": values})
+		if err != nil {
+			return err
+		}
+		key, err := m.target.createIssue(json)
+		if err != nil || key == "" {
+			return err
+		}
+		link = &mirrorLink{Key: key}
+		links[m.target.endpoint.String()] = link
+		// record the pairing straight away so a failure below does not
+		// lead to a duplicate on the next run
+		if err := m.source.saveMirrorLinks(source, links); err != nil {
+			return err
+		}
+		if !m.source.GetOptBool("quiet", false) {
+			fmt.Printf("OK %s %s %s/browse/%s\n", source, key, m.target.endpoint, key)
+		}
+	} else {
+		data, err := m.target.viewIssue(link.Key)
+		if err != nil {
+			return err
+		}
+		targetData, _ := data.(map[string]interface{})
+		targetFields, _ := targetData["fields"].(map[string]interface{})
+		uri := fmt.Sprintf("%s/rest/api/2/issue/%s/editmeta", m.target.endpoint, link.Key)
+		editmeta, err := responseToJSON(m.target.get(uri))
+		if err != nil {
+			return err
+		}
+		meta, _ := editmeta.(map[string]interface{})
+		values, dropped := mapFields(fields, meta)
+		if len(dropped) > 0 {
+			log.Warningf("%s fields not editable on %s, skipping: %s", source, link.Key, strings.Join(dropped, ", "))
+		}
+		changed := map[string]interface{}{}
+		for id, value := range values {
+			if !syncEqual(value, targetFields[id]) {
+				changed[id] = value
+			}
+		}
+		if len(changed) > 0 {
+			if err := m.target.updateIssue(link.Key, map[string]interface{}{"fields": changed}); err != nil {
+				return err
+			}
+			if !m.source.GetOptBool("quiet", false) {
+				fmt.Printf("OK %s %s %s/browse/%s\n", source, link.Key, m.target.endpoint, link.Key)
+			}
+		} else {
+			log.Debugf("%s already matches %s", link.Key, source)
+		}
+	}
+
+	if err := m.mirrorStatus(issueData, link.Key); err != nil {
+		return err
+	}
+	// every copied comment is recorded as soon as it is made, so a failure
+	// part way does not lead to duplicates on the next run
+	if err := m.mirrorComments(source, link, func() error {
+		return m.source.saveMirrorLinks(source, links)
+	}); err != nil {
+		return err
+	}
+
+	// the counterpart records the same pairing from its side
+	targetLinks, err := m.target.mirrorLinks(link.Key)
+	if err != nil {
+		return err
+	}
+	reversed := &mirrorLink{Key: source, Comments: map[string]string{}}
+	for sourceComment, targetComment := range link.Comments {
+		reversed.Comments[targetComment] = sourceComment
+	}
+	targetLinks[m.source.endpoint.String()] = reversed
+	return m.target.saveMirrorLinks(link.Key, targetLinks)
+}
+
+// mirrorFields will return the mapped fields of the source issue by their
+// target field id
+func (m *mirror) mirrorFields(issueData map[string]interface{}) map[string]interface{} {
+	names := m.mapping.Fields
+	if len(names) == 0 {
+		names = map[string]string{}
+		for _, name := range defaultMirrorFields {
+			names[name] = name
+		}
+	}
+	sourceFields, _ := issueData["fields"].(map[string]interface{})
+	fields := map[string]interface{}{}
+	for sourceName, targetName := range names {
+		sourceID := m.source.resolveField(sourceName, nil)
+		if sourceID == "" {
+			log.Warningf("Unknown field '%s' on %s, skipping", sourceName, m.source.endpoint)
+			continue
+		}
+		targetID := m.target.resolveField(targetName, nil)
+		if targetID == "" {
+			log.Warningf("Unknown field '%s' on %s, skipping", targetName, m.target.endpoint)
+			continue
+		}
+		if value, ok := m.mirrorValue(sourceFields[sourceID]); ok {
+			fields[targetID] = value
+		}
+	}
+	return fields
+}
+
+// mirrorValue will map the users in the value, and drop the ids of objects
+// that can be matched by name or value since ids differ between instances
+func (m *mirror) mirrorValue(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		values := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if mapped, ok := m.mirrorValue(item); ok {
+				values = append(values, mapped)
+			}
+		}
+		return values, true
+	case map[string]interface{}:
+		if _, ok := v["displayName"]; ok {
+			if _, ok := v["active"]; ok {
+				return m.mirrorUser(v)
+			}
+		}
+		mapped := map[string]interface{}{}
+		for key, item := range v {
+			if key == "self" {
+				continue
+			}
+			if child, ok := item.(map[string]interface{}); ok && key == "child" {
+				item, _ = m.mirrorValue(child)
+			}
+			mapped[key] = item
+		}
+		for _, key := range []string{"key", "name", "value"} {
+			if _, ok := mapped[key]; ok {
+				delete(mapped, "id")
+				break
+			}
+		}
+		return mapped, true
+	}
+	return value, true
+}
+
+func (m *mirror) mirrorUser(user map[string]interface{}) (interface{}, bool) {
+	name, _ := user["name"].(string)
+	if name == "" {
+		name, _ = user["accountId"].(string)
+	}
+	if len(m.mapping.Users) > 0 {
+		mapped, ok := m.mapping.Users[name]
+		if !ok {
+			log.Warningf("No user mapping for '%s', skipping", name)
+			return nil, false
+		}
+		name = mapped
+	}
+	if strings.HasPrefix(name, "accountId:") {
+		return map[string]interface{}{"accountId": strings.TrimPrefix(name, "accountId:")}, true
+	}
+	return map[string]interface{}{"name": name}, true
+}
+
+// mirrorStatus will transition the counterpart to the mapped status of the
+// source issue
+func (m *mirror) mirrorStatus(issueData map[string]interface{}, key string) error {
+	status := issueField(issueData, "status", "name")
+	if mapped, ok := m.mapping.Statuses[status]; ok {
+		status = mapped
+	}
+	if status == "" || m.target.getOptBool("dryrun", false) {
+		return nil
+	}
+	data, err := m.target.viewIssue(key)
+	if err != nil {
+		return err
+	}
+	targetData, _ := data.(map[string]interface{})
+	if strings.EqualFold(issueField(targetData, "status", "name"), status) {
+		return nil
+	}
+	quiet := m.target.GetOptBool("quiet", false)
+	m.target.opts["quiet"] = true
+	if err := m.target.moveTo(key, status, false); err != nil {
+		log.Warningf("Unable to transition %s to %s: %s", key, status, err)
+	}
+	m.target.opts["quiet"] = quiet
+	return nil
+}
+
+// mirrorComments will copy the comments that have not been mirrored yet
+// between the issue and its counterpart.  Copied comments are recorded in
+// the link and saved after each copy, and comments that are copies
+// themselves are never copied back.
+func (m *mirror) mirrorComments(source string, link *mirrorLink, save func() error) error {
+	if link.Comments == nil {
+		link.Comments = map[string]string{}
+	}
+	sourceComments, err := m.source.issueComments(source)
+	if err != nil {
+		return err
+	}
+	targetComments, err := m.target.issueComments(link.Key)
+	if err != nil {
+		return err
+	}
+	mirrored := map[string]bool{}
+	for sourceID, targetID := range link.Comments {
+		mirrored[sourceID] = true
+		mirrored[targetID] = true
+	}
+
+	for _, cm := range sourceComments {
+		comment, _ := cm.(map[string]interface{})
+		id := fmt.Sprintf("%v", comment["id"])
+		if mirrored[id] || strings.HasPrefix(fmt.Sprintf("%v", comment["body"]), mirrorCommentPrefix) {
+			continue
+		}
+		newID, err := m.target.mirrorComment(link.Key, source, comment)
+		if err != nil {
+			return err
+		}
+		if newID != "" {
+			link.Comments[id] = newID
+			if err := save(); err != nil {
+				return err
+			}
+		}
+	}
+	for _, cm := range targetComments {
+		comment, _ := cm.(map[string]interface{})
+		id := fmt.Sprintf("%v", comment["id"])
+		if mirrored[id] || strings.HasPrefix(fmt.Sprintf("%v", comment["body"]), mirrorCommentPrefix) {
+			continue
+		}
+		newID, err := m.source.mirrorComment(source, link.Key, comment)
+		if err != nil {
+			return err
+		}
+		if newID != "" {
+			link.Comments[newID] = id
+			if err := save(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mirrorComment will add a copy of the comment from the other issue, and
+// return the id of the copy
+func (c *Cli) mirrorComment(issue string, from string, comment map[string]interface{}) (string, error) {
+	author, _ := comment["author"].(map[string]interface{})
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s/comment", c.endpoint, issue)
+	data, err := c.postCreated(uri, map[string]interface{}{
+		"body": fmt.Sprintf("%s%s, %v wrote on %v:\n\n%v", mirrorCommentPrefix, from, author["displayName"], comment["created"], comment["body"]),
+	})
+	if err != nil || data == nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v", data.(map[string]interface{})["id"]), nil
+}
+
+// mirrorLinks will return the counterparts of the issue by their endpoint
+// from the "go-jira.mirror" entity property
+func (c *Cli) mirrorLinks(issue string) (map[string]*mirrorLink, error) {
+	links := map[string]*mirrorLink{}
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s/properties", c.endpoint, issue)
+	data, err := responseToJSON(c.get(uri))
+	if err != nil {
+		return nil, err
+	}
+	keys, _ := data.(map[string]interface{})["keys"].([]interface{})
+	found := false
+	for _, k := range keys {
+		if key, ok := k.(map[string]interface{}); ok && key["key"] == mirrorProperty {
+			found = true
+		}
+	}
+	if !found {
+		return links, nil
+	}
+	data, err = responseToJSON(c.get(uri + "/" + mirrorProperty))
+	if err != nil {
+		return nil, err
+	}
+	content, err := json.Marshal(data.(map[string]interface{})["value"])
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &links); err != nil {
+		log.Warningf("Ignoring invalid %s property on %s: %s", mirrorProperty, issue, err)
+		return map[string]*mirrorLink{}, nil
+	}
+	return links, nil
+}
+
+func (c *Cli) saveMirrorLinks(issue string, links map[string]*mirrorLink) error {
+	json, err := jsonEncode(links)
+	if err != nil {
+		return err
+	}
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s/properties/%s", c.endpoint, issue, mirrorProperty)
+	if c.getOptBool("dryrun", false) {
+		log.Debugf("PUT: %s", json)
+		log.Debugf("Dryrun mode, skipping PUT")
+		return nil
+	}
+	resp, err := c.put(uri, json)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 200 && resp.StatusCode != 201 {
+		logBuffer := bytes.NewBuffer(make([]byte, 0))
+		resp.Write(logBuffer)
+		err := fmt.Errorf("Unexpected Response From PUT")
+		log.Errorf("%s:\n%s", err, logBuffer)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package jira
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMirrorCommentsSavedEach(t *testing.T) {
+	copied := 0
+	server := newTestJira(t, map[string]testJiraHandler{
+		"GET /rest/api/2/issue/GOJIRA-1/comment": testJiraRespond(200, map[string]interface{}{
+			"comments": []interface{}{
+				map[string]interface{}{"id": "1", "body": "first"},
+				map[string]interface{}{"id": "2", "body": "second"},
+			},
+		}),
+		"GET /rest/api/2/issue/OTHER-1/comment": testJiraRespond(200, map[string]interface{}{"comments": []interface{}{}}),
+		"POST /rest/api/2/issue/OTHER-1/comment": func(*http.Request, interface{}) (int, interface{}) {
+			copied++
+			if copied > 1 {
+				return 500, nil
+			}
+			return 201, map[string]interface{}{"id": "100"}
+		},
+		"PUT /rest/api/2/issue/GOJIRA-1/properties/" + mirrorProperty: testJiraRespond(200, nil),
+	})
+	defer server.Close()
+
+	c := server.cli(map[string]interface{}{"quiet": true})
+	m := &mirror{source: c, target: c}
+	link := &mirrorLink{Key: "OTHER-1"}
+	links := map[string]*mirrorLink{"other": link}
+	err := m.mirrorComments("GOJIRA-1", link, func() error {
+		return c.saveMirrorLinks("GOJIRA-1", links)
+	})
+	if err == nil {
+		t.Fatal("expected the second comment to fail")
+	}
+	saved := server.sent("PUT /rest/api/2/issue/GOJIRA-1/properties/" + mirrorProperty)
+	if len(saved) != 1 {
+		t.Fatalf("expected the first comment to be saved, got %d saves", len(saved))
+	}
+	comments := saved[0].(map[string]interface{})["other"].(map[string]interface{})["comments"]
+	if comments.(map[string]interface{})["1"] != "100" {
+		t.Errorf("expected comment 1 to be paired with 100, got %v", comments)
+	}
+}
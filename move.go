@@ -0,0 +1,430 @@
+package jira
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/Netflix-Skunkworks/go-jira.v0/data"
+)
+
+// issueTypeStatuses will return the statuses that the issuetype can have in
+// the given project
+func (c *Cli) issueTypeStatuses(project string, issuetype string) ([]interface{}, error) {
+	uri := fmt.Sprintf("%s/rest/api/2/project/%s/statuses", c.endpoint, project)
+	data, err := responseToJSON(c.get(uri))
+	if err != nil {
+		return nil, err
+	}
+	types, _ := data.([]interface{})
+	for _, t := range types {
+		if issueType, ok := t.(map[string]interface{}); ok && issueType["name"] == issuetype {
+			statuses, _ := issueType["statuses"].([]interface{})
+			return statuses, nil
+		}
+	}
+	return nil, nil
+}
+
+// missingRequiredFields will return the required fields from the create
+// metadata that have no value in fields and no default value
+func missingRequiredFields(fields map[string]interface{}, meta map[string]interface{}) map[string]interface{} {
+	missing := map[string]interface{}{}
+	metaFields, _ := meta["fields"].(map[string]interface{})
+	for name, m := range metaFields {
+		fieldMeta, _ := m.(map[string]interface{})
+		if required, _ := fieldMeta["required"].(bool); !required {
+			continue
+		}
+		if hasDefault, _ := fieldMeta["hasDefaultValue"].(bool); hasDefault {
+			continue
+		}
+		if _, ok := fields[name]; !ok {
+			missing[name] = fieldMeta["name"]
+		}
+	}
+	return missing
+}
+
+// CmdMove will move the issue to the project and issuetype given by the
+// 'project' and 'issuetype' options on the command line.  Fields are mapped using the create
+// metadata of the target, and the "move" template is opened for editing when
+// the target has required fields without a value, or when the current status
+// of the issue does not exist in the target workflow.  The bulk move api is
+// used when the jira service supports it, otherwise the issue is cloned into
+// the target, linked to the original, and the original is closed.
+func (c *Cli) CmdMove(issue string) error {
+	log.Debugf("move called")
+	data, err := c.ViewIssue(issue)
+	if err != nil {
+		return err
+	}
+	sourceData, ok := data.(map[string]interface{})
+	if !ok || sourceData["key"] == nil {
+		err := fmt.Errorf("Issue %s not found", issue)
+		log.Errorf("%s", err)
+		return err
+	}
+	source := sourceData["key"].(string)
+
+	if parent := issueField(sourceData, "parent", "key"); parent != "" {
+		err := fmt.Errorf("Issue %s is a subtask of %s, move %s instead", source, parent, parent)
+		log.Errorf("%s", err)
+		return err
+	}
+
+	// only the command line options are used, the configured defaults are
+	// not where an issue is meant to be moved
+	project := strings.ToUpper(c.getCmdlineOptString("project", issueField(sourceData, "project", "key")))
+	issuetype := c.getCmdlineOptString("issuetype", issueField(sourceData, "issuetype", "name"))
+	if project == issueField(sourceData, "project", "key") && issuetype == issueField(sourceData, "issuetype", "name") {
+		err := fmt.Errorf("Issue %s is already a %s in %s", source, issuetype, project)
+		log.Errorf("%s", err)
+		return err
+	}
+
+	fields, meta, err := c.cloneIssueFields(sourceData, project, issuetype)
+	if err != nil {
+		return err
+	}
+	missing := missingRequiredFields(fields, meta)
+	required := map[string]interface{}{}
+	for name := range missing {
+		required[name] = nil
+	}
+
+	statuses, err := c.issueTypeStatuses(project, issuetype)
+	if err != nil {
+		return err
+	}
+	status := issueField(sourceData, "status", "name")
+	statusNames := []string{}
+	statusFound := false
+	for _, s := range statuses {
+		if name, ok := s.(map[string]interface{})["name"].(string); ok {
+			statusNames = append(statusNames, name)
+			statusFound = statusFound || name == status
+		}
+	}
+	sort.Strings(statusNames)
+
+	if (len(missing) > 0 || (len(statuses) > 0 && !statusFound)) && !c.getOptBool("noedit", false) {
+		c.opts["edit"] = true
+	}
+
+	moveData := map[string]interface{}{
+		"source":    sourceData,
+		"project":   project,
+		"issuetype": issuetype,
+		"status":    status,
+		"statuses":  statusNames,
+		"fields":    required,
+		"required":  missing,
+		"meta":      meta,
+		"overrides": c.opts,
+	}
+
+	return c.editTemplate(
+		c.getTemplate("move"),
+		fmt.Sprintf("%s-move-", source),
+		moveData,
+		func(content string) error {
+			edited := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(content), &edited); err != nil {
+				return err
+			}
+			editedFields, _ := edited["fields"].(map[string]interface{})
+			for name := range missing {
+				if _, ok := editedFields[name]; !ok {
+					return fmt.Errorf("Required field %s (%s) has no value", name, missing[name])
+				}
+			}
+
+			status, _ := edited["status"].(string)
+			var statusID string
+			for _, s := range statuses {
+				if targetStatus, ok := s.(map[string]interface{}); ok && targetStatus["name"] == status {
+					statusID, _ = targetStatus["id"].(string)
+				}
+			}
+			if len(statuses) > 0 && statusID == "" {
+				return fmt.Errorf("Invalid status '%s', Available: %s", status, strings.Join(statusNames, ", "))
+			}
+
+			issuetypeID, _ := meta["id"].(string)
+			key, supported, err := c.bulkMove(sourceData, project, issuetypeID, statusID, editedFields)
+			if err != nil {
+				return err
+			}
+			if !supported {
+				for name, value := range editedFields {
+					fields[name] = value
+				}
+				if key, err = c.moveByClone(sourceData, project, fields, status); err != nil {
+					return err
+				}
+			}
+			if key == "" {
+				// dryrun
+				return nil
+			}
+
+			link := fmt.Sprintf("%s/browse/%s", c.endpoint, key)
+			c.Browse(key)
+			c.SaveData(map[string]string{
+				"issue": key,
+				"link":  link,
+			})
+			if !c.GetOptBool("quiet", false) {
+				fmt.Printf("OK %s %s\n", key, link)
+			}
+			return nil
+		},
+	)
+}
+
+// bulkMove will move the issue with the bulk move api, returning the new key
+// of the issue.  If the jira service does not support the bulk move api then
+// false is returned.
+func (c *Cli) bulkMove(sourceData map[string]interface{}, project string, issuetypeID string, statusID string, fields map[string]interface{}) (string, bool, error) {
+	source := sourceData["key"].(string)
+
+	mapping := map[string]interface{}{
+		"inferClassificationDefaults": true,
+		"inferFieldDefaults":          true,
+		"inferStatusDefaults":         statusID == "",
+		"inferSubtaskTypeDefault":     true,
+		"issueIdsOrKeys":              []string{source},
+	}
+	if len(fields) > 0 {
+		mandatory := map[string]interface{}{}
+		for name, value := range fields {
+			mandatory[name] = map[string]interface{}{
+				"retain": false,
+				"type":   "raw",
+				"value":  value,
+			}
+		}
+		mapping["targetMandatoryFields"] = []interface{}{
+			map[string]interface{}{
+				"fields": mandatory,
+			},
+		}
+	}
+	if statusID != "" {
+		sourceID, _ := strconv.Atoi(fmt.Sprintf("%v", sourceData["id"]))
+		mapping["targetStatus"] = []interface{}{
+			map[string]interface{}{
+				"statuses": map[string]interface{}{
+					statusID: []interface{}{
+						map[string]interface{}{
+							"issueIds": []int{sourceID},
+							"statusId": issueField(sourceData, "status", "id"),
+						},
+					},
+				},
+			},
+		}
+	}
+
+	json, err := jsonEncode(map[string]interface{}{
+		"sendBulkNotification": true,
+		"targetToSourcesMapping": map[string]interface{}{
+			fmt.Sprintf("%s,%s", project, issuetypeID): mapping,
+		},
+	})
+	if err != nil {
+		return "", true, err
+	}
+
+	uri := fmt.Sprintf("%s/rest/api/3/bulk/issues/move", c.endpoint)
+	if c.getOptBool("dryrun", false) {
+		log.Debugf("POST: %s", json)
+		log.Debugf("Dryrun mode, skipping POST")
+		return "", true, nil
+	}
+	resp, err := c.post(uri, json)
+	if err != nil {
+		return "", true, err
+	}
+	if resp.StatusCode == 404 {
+		log.Debugf("Bulk move not supported by %s", c.endpoint)
+		return "", false, nil
+	}
+	if resp.StatusCode != 201 {
+		logBuffer := bytes.NewBuffer(make([]byte, 0))
+		resp.Write(logBuffer)
+		err := fmt.Errorf("Unexpected Response From POST")
+		log.Errorf("%s:\n%s", err, logBuffer)
+		return "", true, err
+	}
+
+	// response: {"taskId":"10641"}
+	data, err := responseToJSON(resp, nil)
+	if err != nil {
+		return "", true, err
+	}
+	task, _ := data.(map[string]interface{})
+	taskID, ok := task["taskId"].(string)
+	if !ok {
+		err := fmt.Errorf("Missing task id in bulk move response")
+		log.Errorf("%s", err)
+		return "", true, err
+	}
+	if err := c.waitForBulkTask(taskID); err != nil {
+		return "", true, err
+	}
+
+	// jira will find the issue by the old key and return the new key
+	data, err = c.ViewIssue(source)
+	if err != nil {
+		return "", true, err
+	}
+	moved, _ := data.(map[string]interface{})
+	key, _ := moved["key"].(string)
+	return key, true, nil
+}
+
+// waitForBulkTask will poll the bulk operation task until it is finished
+func (c *Cli) waitForBulkTask(taskID string) error {
+	uri := fmt.Sprintf("%s/rest/api/3/bulk/queue/%s", c.endpoint, taskID)
+	for {
+		data, err := responseToJSON(c.get(uri))
+		if err != nil {
+			return err
+		}
+		task, _ := data.(map[string]interface{})
+		switch task["status"] {
+		case "ENQUEUED", "RUNNING":
+			log.Debugf("Bulk task %s is %s", taskID, task["status"])
+			time.Sleep(time.Second)
+		case "COMPLETE":
+			if failed, ok := task["failedAccessibleIssues"].(map[string]interface{}); ok && len(failed) > 0 {
+				for issue, reasons := range failed {
+					log.Errorf("%s: %v", issue, reasons)
+				}
+				err := fmt.Errorf("Bulk task %s failed to move issues", taskID)
+				log.Errorf("%s", err)
+				return err
+			}
+			return nil
+		default:
+			err := fmt.Errorf("Bulk task %s finished with status %v", taskID, task["status"])
+			log.Errorf("%s", err)
+			return err
+		}
+	}
+}
+
+// moveByClone is used to move an issue when the bulk move api is not
+// available.  A new issue is created in the target with the given fields,
+// the links and subtasks are recreated, and the original issue and its open
+// subtasks are closed.  The new issue records the old key in a comment.
+func (c *Cli) moveByClone(sourceData map[string]interface{}, project string, fields map[string]interface{}, status string) (string, error) {
+	source := sourceData["key"].(string)
+	log.Infof("Bulk move not available, cloning %s into %s", source, project)
+
+	json, err := jsonEncode(map[string]interface{}{
+		"fields": fields,
+	})
+	if err != nil {
+		return "", err
+	}
+	key, err := c.createIssue(json)
+	if err != nil || key == "" {
+		return "", err
+	}
+
+	subtaskKeys, err := c.cloneRelations(sourceData, key, project, true, true)
+	if err != nil {
+		return key, err
+	}
+	if err := c.addComment(key, fmt.Sprintf("Moved from %s", source)); err != nil {
+		return key, err
+	}
+
+	data, err := c.ViewIssue(key)
+	if err != nil {
+		return key, err
+	}
+	created, _ := data.(map[string]interface{})
+	if status != "" && status != issueField(created, "status", "name") {
+		transitions, err := c.ValidTransitions(key)
+		if err != nil {
+			return key, err
+		}
+		var found bool
+		for _, trans := range transitions {
+			if trans.To != nil && trans.To.Name == status {
+				if err := c.transitionIssue(key, trans, c.issueWorkflowProfile(created), ""); err != nil {
+					return key, err
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			log.Warningf("Unable to transition %s to status %s", key, status)
+		}
+	}
+
+	// the subtasks are closed first, some workflows will not close an
+	// issue with open subtasks
+	sourceFields, _ := sourceData["fields"].(map[string]interface{})
+	subtasks, _ := sourceFields["subtasks"].([]interface{})
+	for _, s := range subtasks {
+		subtask, _ := s.(map[string]interface{})
+		subtaskKey := fmt.Sprintf("%v", subtask["key"])
+		movedKey, ok := subtaskKeys[subtaskKey]
+		if !ok {
+			continue
+		}
+		subtaskData, err := c.issueWithTransitions(subtaskKey)
+		if err != nil {
+			return key, err
+		}
+		if syncStatusMatches(subtaskData, "done") {
+			// already closed
+			continue
+		}
+		if err := c.closeMovedIssue(subtaskKey, subtaskData, issueTransitions(subtaskData), movedKey); err != nil {
+			return key, err
+		}
+	}
+
+	transitions, err := c.ValidTransitions(source)
+	if err != nil {
+		return key, err
+	}
+	return key, c.closeMovedIssue(source, sourceData, transitions, key)
+}
+
+// closeMovedIssue will close the issue that was moved by cloning it to key,
+// with the close or done transition of its workflow profile
+func (c *Cli) closeMovedIssue(issue string, issueData map[string]interface{}, transitions jiradata.Transitions, key string) error {
+	profile := c.issueWorkflowProfile(issueData)
+	var trans *jiradata.Transition
+	for _, name := range []string{
+		c.GetOptString("closeTransition", c.transitionAlias(issueData, profile, "close")),
+		c.transitionAlias(issueData, profile, "done"),
+	} {
+		if len(transitions.Matches(name)) > 0 {
+			var err error
+			if trans, err = chooseTransition(transitions, name); err != nil {
+				log.Errorf("%s", err)
+				return err
+			}
+			break
+		}
+	}
+	if trans == nil {
+		err := fmt.Errorf("Unable to close %s, no close or done transition available", issue)
+		log.Errorf("%s", err)
+		return err
+	}
+	return c.transitionIssue(issue, trans, profile, fmt.Sprintf("Moved to %s", key))
+}
@@ -0,0 +1,119 @@
+package jira
+
+import (
+	"net/http"
+	"testing"
+)
+
+func testMoveMeta(r *http.Request, body interface{}) (int, interface{}) {
+	return 200, map[string]interface{}{
+		"projects": []interface{}{map[string]interface{}{
+			"issuetypes": []interface{}{map[string]interface{}{
+				"id": "1",
+				"fields": map[string]interface{}{
+					"summary": map[string]interface{}{"name": "Summary", "schema": map[string]interface{}{"type": "string"}},
+				},
+			}},
+		}},
+	}
+}
+
+func testMoveIssue(key string, subtasks ...interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"id":  "10000",
+		"key": key,
+		"fields": map[string]interface{}{
+			"summary":   key,
+			"project":   map[string]interface{}{"key": "GOJIRA"},
+			"issuetype": map[string]interface{}{"name": "Task"},
+			"status":    map[string]interface{}{"name": "To Do", "statusCategory": map[string]interface{}{"key": "new"}},
+			"subtasks":  subtasks,
+		},
+		"transitions": []interface{}{
+			map[string]interface{}{"id": "31", "name": "Done", "to": map[string]interface{}{"name": "Done"}},
+		},
+	}
+}
+
+func TestMoveCommandLineProject(t *testing.T) {
+	metaProjects := []string{}
+	server := newTestJira(t, map[string]testJiraHandler{
+		"GET /rest/api/2/issue/GOJIRA-1": testJiraRespond(200, testMoveIssue("GOJIRA-1")),
+		"GET /rest/api/2/issue/createmeta": func(r *http.Request, body interface{}) (int, interface{}) {
+			metaProjects = append(metaProjects, r.URL.Query().Get("projectKeys"))
+			return testMoveMeta(r, body)
+		},
+		"GET /rest/api/2/project/GOJIRA/statuses": testJiraRespond(200, []interface{}{}),
+		"POST /rest/api/3/bulk/issues/move":       testJiraRespond(201, map[string]interface{}{"taskId": "1"}),
+		"GET /rest/api/3/bulk/queue/1":            testJiraRespond(200, map[string]interface{}{"status": "COMPLETE"}),
+	})
+	defer server.Close()
+
+	// the configured default project is not where the issue goes, only the
+	// issuetype was given on the command line
+	c := server.cli(map[string]interface{}{"project": "OTHER", "issuetype": "Bug", "noedit": true, "edit": false, "quiet": true})
+	c.SetCommandLineOptions(map[string]interface{}{"issuetype": "Bug"})
+	if err := c.CmdMove("GOJIRA-1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(metaProjects) != 1 || metaProjects[0] != "GOJIRA" {
+		t.Errorf("expected the create metadata of GOJIRA, got %v", metaProjects)
+	}
+	moves := server.sent("POST /rest/api/3/bulk/issues/move")
+	if len(moves) != 1 {
+		t.Fatalf("expected 1 bulk move, got %d", len(moves))
+	}
+	mapping := moves[0].(map[string]interface{})["targetToSourcesMapping"].(map[string]interface{})
+	if _, ok := mapping["GOJIRA,1"]; !ok {
+		t.Errorf("expected a move to GOJIRA, got %v", mapping)
+	}
+}
+
+func TestMoveByCloneClosesSubtasks(t *testing.T) {
+	created := []string{"NEW-1", "NEW-2"}
+	subtask := testMoveIssue("GOJIRA-2")
+	subtask["fields"].(map[string]interface{})["issuetype"] = map[string]interface{}{"name": "Sub-task"}
+	server := newTestJira(t, map[string]testJiraHandler{
+		"GET /rest/api/2/issue/GOJIRA-1": testJiraRespond(200, testMoveIssue("GOJIRA-1",
+			map[string]interface{}{"key": "GOJIRA-2"},
+		)),
+		"GET /rest/api/2/issue/GOJIRA-2":              testJiraRespond(200, subtask),
+		"GET /rest/api/2/issue/createmeta":            testMoveMeta,
+		"GET /rest/api/2/project/NEW/statuses":        testJiraRespond(200, []interface{}{}),
+		"POST /rest/api/3/bulk/issues/move":           testJiraRespond(404, nil),
+		"GET /rest/api/2/issueLinkType":               testJiraRespond(200, map[string]interface{}{"issueLinkTypes": []interface{}{map[string]interface{}{"name": "Cloners", "inward": "is cloned by", "outward": "clones"}}}),
+		"POST /rest/api/2/issueLink":                  testJiraRespond(201, nil),
+		"POST /rest/api/2/issue/NEW-1/comment":        testJiraRespond(201, nil),
+		"GET /rest/api/2/issue/NEW-1":                 testJiraRespond(200, testMoveIssue("NEW-1")),
+		"GET /rest/api/2/issue/GOJIRA-1/transitions":  testJiraRespond(200, testMoveIssue("GOJIRA-1")),
+		"POST /rest/api/2/issue/GOJIRA-1/transitions": testJiraRespond(204, nil),
+		"POST /rest/api/2/issue/GOJIRA-2/transitions": testJiraRespond(204, nil),
+		"POST /rest/api/2/issue": func(*http.Request, interface{}) (int, interface{}) {
+			key := created[0]
+			created = created[1:]
+			return 201, map[string]interface{}{"key": key}
+		},
+	})
+	defer server.Close()
+
+	c := server.cli(map[string]interface{}{"noedit": true, "edit": false, "quiet": true})
+	c.SetCommandLineOptions(map[string]interface{}{"project": "NEW"})
+	if err := c.CmdMove("GOJIRA-1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 0 {
+		t.Errorf("expected the issue and its subtask to be cloned, %v were not created", created)
+	}
+	for issue, moved := range map[string]string{"GOJIRA-1": "NEW-1", "GOJIRA-2": "NEW-2"} {
+		closed := server.sent("POST /rest/api/2/issue/" + issue + "/transitions")
+		if len(closed) != 1 {
+			t.Errorf("expected %s to be closed, got %v", issue, closed)
+			continue
+		}
+		update := closed[0].(map[string]interface{})["update"].(map[string]interface{})
+		comment := update["comment"].([]interface{})[0].(map[string]interface{})["add"].(map[string]interface{})["body"]
+		if comment != "Moved to "+moved {
+			t.Errorf("expected %s to be moved to %s, got %v", issue, moved, comment)
+		}
+	}
+}
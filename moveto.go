@@ -0,0 +1,250 @@
+package jira
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/Netflix-Skunkworks/go-jira.v0/data"
+)
+
+// statusMatches will return true if the status name, status category name
+// or status category key matches the target
+func statusMatches(status *jiradata.Status, target string) bool {
+	if status == nil {
+		return false
+	}
+	if strings.EqualFold(status.Name, target) {
+		return true
+	}
+	if category := status.StatusCategory; category != nil {
+		return strings.EqualFold(category.Name, target) || strings.EqualFold(category.Key, target)
+	}
+	return false
+}
+
+// statusSample will return the key of another issue with the same project
+// and issuetype that is in the given status, so the transitions available
+// from that status can be inspected without changing the issue being moved.
+// When issue is empty any issue in the status is returned.
+func (c *Cli) statusSample(issue string, project string, issuetype string, status string) (string, error) {
+	json, err := jsonEncode(map[string]interface{}{
+		"jql":        statusSampleQuery(issue, project, issuetype, status),
+		"maxResults": 1,
+		"fields":     []string{"status"},
+	})
+	if err != nil {
+		return "", err
+	}
+	uri := fmt.Sprintf("%s/rest/api/2/search", c.endpoint)
+	data, err := responseToJSON(c.post(uri, json))
+	if err != nil {
+		return "", err
+	}
+	results, _ := data.(map[string]interface{})
+	issues, _ := results["issues"].([]interface{})
+	for _, i := range issues {
+		if sample, ok := i.(map[string]interface{}); ok {
+			key, _ := sample["key"].(string)
+			return key, nil
+		}
+	}
+	return "", nil
+}
+
+// statusSampleQuery will return the JQL for statusSample, the values are
+// quoted so names with quotes or reserved words can not break the query
+func statusSampleQuery(issue string, project string, issuetype string, status string) string {
+	jql := NewJQLBuilder().
+		Where(JQLEquals("project", JQLString(project))).
+		Where(JQLEquals("issuetype", JQLString(issuetype))).
+		Where(JQLEquals("status", JQLString(status)))
+	if issue != "" {
+		clause, _ := JQLCompare("key", "!=", JQLString(issue))
+		jql.Where(clause)
+	}
+	return jql.String()
+}
+
+// TransitionPath will find the shortest sequence of transitions that will take
+// the issue from its current status to the target status or status category.
+// The transitions from the current status come from the issue itself, the
+// transitions from any other status are discovered from another issue of the
+// same project and issuetype that is currently in that status.
+func (c *Cli) TransitionPath(issue string, target string) (jiradata.Transitions, error) {
+	data, err := c.ViewIssue(issue)
+	if err != nil {
+		return nil, err
+	}
+	issueData, _ := data.(map[string]interface{})
+	current := issueField(issueData, "status", "name")
+	project := issueField(issueData, "project", "key")
+	issuetype := issueField(issueData, "issuetype", "name")
+
+	type step struct {
+		status string
+		path   jiradata.Transitions
+	}
+	visited := map[string]bool{current: true}
+	queue := []step{{status: current}}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+
+		from := issue
+		if next.status != current {
+			if from, err = c.statusSample(issue, project, issuetype, next.status); err != nil {
+				return nil, err
+			}
+			if from == "" {
+				log.Debugf("No %s %s issue in status %s, unable to follow transitions from it", project, issuetype, next.status)
+				continue
+			}
+		}
+		transitions, err := c.ValidTransitions(from)
+		if err != nil {
+			return nil, err
+		}
+		for _, trans := range transitions {
+			if trans.To == nil || visited[trans.To.Name] {
+				continue
+			}
+			path := append(append(jiradata.Transitions{}, next.path...), trans)
+			if statusMatches(trans.To, target) {
+				return path, nil
+			}
+			visited[trans.To.Name] = true
+			queue = append(queue, step{status: trans.To.Name, path: path})
+		}
+	}
+	err = fmt.Errorf("No transitions found to take %s from %s to %s", issue, current, target)
+	log.Errorf("%s", err)
+	return nil, err
+}
+
+// needsScreen will return true if the transition has required fields that
+// cannot be filled in without prompting the user
+func (c *Cli) needsScreen(trans *jiradata.Transition, profile *WorkflowProfile) bool {
+	for name, field := range trans.Fields {
+		if !field.Required || field.HasDefaultValue {
+			continue
+		}
+		if name == "resolution" && c.defaultResolution(profile) != "" {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// CmdMoveTo will transition the issue through the shortest path of
+// transitions to the target status or status category.  The planned path is
+// shown, then each step is applied.  Steps with required fields open the
+// "transition" template for editing, the others are applied directly.  The
+// 'comment' option is added on the final step.
+func (c *Cli) CmdMoveTo(issue string, target string) error {
+	log.Debugf("move-to called")
+	return c.moveTo(issue, target, true)
+}
+
+// moveTo will do the work of CmdMoveTo.  When not interactive (for batch
+// commands like mirror and restore) it never opens the editor or the
+// browser, a step with required fields is an error instead.
+func (c *Cli) moveTo(issue string, target string, interactive bool) error {
+	data, err := c.ViewIssue(issue)
+	if err != nil {
+		return err
+	}
+	issueData, _ := data.(map[string]interface{})
+	if fields, ok := issueData["fields"].(map[string]interface{}); ok {
+		if status, ok := fields["status"].(map[string]interface{}); ok {
+			current := &jiradata.Status{Name: fmt.Sprintf("%v", status["name"])}
+			if category, ok := status["statusCategory"].(map[string]interface{}); ok {
+				current.StatusCategory = &jiradata.StatusCategory{
+					Name: fmt.Sprintf("%v", category["name"]),
+					Key:  fmt.Sprintf("%v", category["key"]),
+				}
+			}
+			if statusMatches(current, target) {
+				if !c.GetOptBool("quiet", false) {
+					fmt.Printf("OK %s %s/browse/%s\n", issue, c.endpoint, issue)
+				}
+				return nil
+			}
+		}
+	}
+
+	path, err := c.TransitionPath(issue, target)
+	if err != nil {
+		return err
+	}
+	if !c.GetOptBool("quiet", false) {
+		steps := []string{issueField(issueData, "status", "name")}
+		for _, trans := range path {
+			steps = append(steps, fmt.Sprintf("-[%s]-> %s", trans.Name, trans.To.Name))
+		}
+		fmt.Printf("%s: %s\n", issue, strings.Join(steps, " "))
+	}
+
+	comment := c.GetOptString("comment", "")
+	profile := c.issueWorkflowProfile(issueData)
+	for i, planned := range path {
+		last := i == len(path)-1
+		if c.getOptBool("dryrun", false) {
+			log.Debugf("Dryrun mode, skipping transition %s", planned.Name)
+			continue
+		}
+		// the transitions are fetched again for each step since the
+		// screens and conditions may differ from the planned issue
+		transitions, err := c.ValidTransitions(issue)
+		if err != nil {
+			return err
+		}
+		var trans *jiradata.Transition
+		for _, t := range transitions {
+			if t.ID == planned.ID || (t.Name == planned.Name && t.To != nil && t.To.Name == planned.To.Name) {
+				trans = t
+				break
+			}
+		}
+		if trans == nil {
+			err := fmt.Errorf("Transition '%s' to %s is not available for %s", planned.Name, planned.To.Name, issue)
+			log.Errorf("%s", err)
+			return err
+		}
+
+		if c.needsScreen(trans, profile) {
+			if !interactive {
+				return fmt.Errorf("Transition '%s' of %s has required fields that need the editor", trans.Name, issue)
+			}
+			if !last {
+				c.opts["comment"] = ""
+			} else {
+				c.opts["comment"] = comment
+			}
+			c.opts["edit"] = true
+			quiet := c.GetOptBool("quiet", false)
+			c.opts["quiet"] = true
+			err = c.CmdTransition(issue, trans.Name)
+			c.opts["quiet"] = quiet
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		stepComment := ""
+		if last {
+			stepComment = comment
+		}
+		if err := c.transitionIssue(issue, trans, profile, stepComment); err != nil {
+			return err
+		}
+	}
+
+	if interactive {
+		c.Browse(issue)
+	}
+	if !c.GetOptBool("quiet", false) {
+		fmt.Printf("OK %s %s/browse/%s\n", issue, c.endpoint, issue)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package jira
+
+import "testing"
+
+func TestStatusSampleQuery(t *testing.T) {
+	query := statusSampleQuery("GOJIRA-1", "GOJIRA", `Bob's "Bug"`, `Won't Do \ Never`)
+	expected := `project = "GOJIRA" AND issuetype = "Bob's \"Bug\"" AND status = "Won't Do \\ Never" AND key != "GOJIRA-1"`
+	if query != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, query)
+	}
+
+	jql, err := ParseJQL(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue := map[string]interface{}{
+		"key": "GOJIRA-2",
+		"fields": map[string]interface{}{
+			"project":   map[string]interface{}{"key": "GOJIRA"},
+			"issuetype": map[string]interface{}{"name": `Bob's "Bug"`},
+			"status":    map[string]interface{}{"name": `Won't Do \ Never`},
+		},
+	}
+	if ok, err := jql.Match(&JQLEnv{}, issue); err != nil || !ok {
+		t.Errorf("expected %s to match, got %t %v", query, ok, err)
+	}
+}
+
+func TestStatusSampleQueryAnyIssue(t *testing.T) {
+	query := statusSampleQuery("", "GOJIRA", "Bug", "To Do")
+	if expected := `project = "GOJIRA" AND issuetype = "Bug" AND status = "To Do"`; query != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, query)
+	}
+}
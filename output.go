@@ -0,0 +1,288 @@
+package jira
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/coryb/yaml.v2"
+)
+
+// outputColumn is a column selected with the 'columns' option, the value is
+// the field with the id, followed through the path (eg status.name)
+type outputColumn struct {
+	name string
+	id   string
+	path []string
+}
+
+// outputWriter writes issues in one of the built-in 'output' formats
+type outputWriter struct {
+	format  string
+	columns []outputColumn
+	out     io.Writer
+	csv     *csv.Writer
+	rows    int
+}
+
+var outputFormats = []string{"csv", "tsv", "jsonl", "yaml", "markdown"}
+
+// outputColumns will resolve the 'columns' option (default: key and the
+// 'queryfields' option) to field ids, fields may be given by display name
+func (c *Cli) outputColumns() []outputColumn {
+	names := c.getOptString("columns", "")
+	if names == "" {
+		names = "key," + strings.Join(c.queryFields(), ",")
+	}
+	columns := []outputColumn{}
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		column := outputColumn{name: name, id: name}
+		switch name {
+		case "key", "id", "self":
+		default:
+			if id := c.resolveField(name, nil); id != "" {
+				column.id = id
+			} else if parts := strings.SplitN(name, ".", 2); len(parts) == 2 {
+				column.id = parts[0]
+				if id := c.resolveField(parts[0], nil); id != "" {
+					column.id = id
+				}
+				column.path = strings.Split(parts[1], ".")
+			}
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// outputFields will return the field ids needed for the columns
+func outputFields(columns []outputColumn) []string {
+	fields := []string{}
+	seen := map[string]bool{}
+	for _, column := range columns {
+		switch column.id {
+		case "key", "id", "self":
+			continue
+		}
+		if !seen[column.id] {
+			seen[column.id] = true
+			fields = append(fields, column.id)
+		}
+	}
+	return fields
+}
+
+func (c *Cli) newOutputWriter(out io.Writer) (*outputWriter, error) {
+	format := strings.ToLower(c.getOptString("output", ""))
+	valid := false
+	for _, f := range outputFormats {
+		valid = valid || f == format
+	}
+	if !valid {
+		err := fmt.Errorf("Invalid output format '%s', Available: %s", format, strings.Join(outputFormats, ", "))
+		log.Errorf("%s", err)
+		return nil, err
+	}
+	w := &outputWriter{
+		format:  format,
+		columns: c.outputColumns(),
+		out:     out,
+	}
+	if format == "csv" || format == "tsv" {
+		w.csv = csv.NewWriter(out)
+		if format == "tsv" {
+			w.csv.Comma = '\t'
+		}
+	}
+	return w, nil
+}
+
+// columnValue will return the value of the column for the issue
+func columnValue(issue map[string]interface{}, column outputColumn) interface{} {
+	var value interface{}
+	switch column.id {
+	case "key", "id", "self":
+		value = issue[column.id]
+	default:
+		fields, _ := issue["fields"].(map[string]interface{})
+		value = fields[column.id]
+	}
+	for _, property := range column.path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[property]
+		case []interface{}:
+			values := make([]interface{}, 0, len(v))
+			for _, item := range v {
+				if m, ok := item.(map[string]interface{}); ok {
+					values = append(values, m[property])
+				}
+			}
+			value = values
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+// formatValue will format a value for the text formats, objects are shown
+// by their name (or value, key or id) and lists are comma separated
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, formatValue(item))
+		}
+		return strings.Join(items, ", ")
+	case map[string]interface{}:
+		for _, key := range []string{"name", "value", "displayName", "key", "id"} {
+			if name, ok := v[key]; ok {
+				return formatValue(name)
+			}
+		}
+	}
+	content, _ := json.Marshal(value)
+	return string(content)
+}
+
+// markdownEscape will escape a value for a markdown table cell
+func markdownEscape(value string) string {
+	value = strings.Replace(value, "|", `\|`, -1)
+	value = strings.Replace(value, "\r\n", "<br>", -1)
+	return strings.Replace(value, "\n", "<br>", -1)
+}
+
+// Write will write the issues, the header is written before the first
+func (w *outputWriter) Write(issues []interface{}) error {
+	if w.rows == 0 {
+		if err := w.header(); err != nil {
+			return err
+		}
+	}
+	for _, i := range issues {
+		issue, _ := i.(map[string]interface{})
+		if err := w.row(issue); err != nil {
+			return err
+		}
+		w.rows++
+	}
+	if w.csv != nil {
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+	return nil
+}
+
+func (w *outputWriter) header() error {
+	names := make([]string, 0, len(w.columns))
+	for _, column := range w.columns {
+		names = append(names, column.name)
+	}
+	switch w.format {
+	case "csv", "tsv":
+		return w.csv.Write(names)
+	case "markdown":
+		separators := make([]string, 0, len(names))
+		for i, name := range names {
+			names[i] = markdownEscape(name)
+			separators = append(separators, "---")
+		}
+		_, err := fmt.Fprintf(w.out, "| %s |\n| %s |\n", strings.Join(names, " | "), strings.Join(separators, " | "))
+		return err
+	}
+	return nil
+}
+
+func (w *outputWriter) row(issue map[string]interface{}) error {
+	switch w.format {
+	case "csv", "tsv":
+		values := make([]string, 0, len(w.columns))
+		for _, column := range w.columns {
+			values = append(values, formatValue(columnValue(issue, column)))
+		}
+		return w.csv.Write(values)
+	case "markdown":
+		values := make([]string, 0, len(w.columns))
+		for _, column := range w.columns {
+			values = append(values, markdownEscape(formatValue(columnValue(issue, column))))
+		}
+		_, err := fmt.Fprintf(w.out, "| %s |\n", strings.Join(values, " | "))
+		return err
+	case "jsonl":
+		// written by hand to keep the columns in order
+		var buffer bytes.Buffer
+		buffer.WriteString("{")
+		for i, column := range w.columns {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			name, _ := json.Marshal(column.name)
+			value, err := json.Marshal(columnValue(issue, column))
+			if err != nil {
+				return err
+			}
+			buffer.Write(name)
+			buffer.WriteString(":")
+			buffer.Write(value)
+		}
+		buffer.WriteString("}\n")
+		_, err := w.out.Write(buffer.Bytes())
+		return err
+	case "yaml":
+		document := yaml.MapSlice{}
+		for _, column := range w.columns {
+			document = append(document, yaml.MapItem{Key: column.name, Value: columnValue(issue, column)})
+		}
+		content, err := yaml.Marshal(document)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w.out, "---\n%s", content)
+		return err
+	}
+	return nil
+}
+
+// outputIssues will write the issues matching the query options in the
+// 'output' format, paging through the search results
+func (c *Cli) outputIssues() error {
+	w, err := c.newOutputWriter(os.Stdout)
+	if err != nil {
+		return err
+	}
+	if err := c.FindAllIssues(outputFields(w.columns), w.Write); err != nil {
+		return err
+	}
+	if w.rows == 0 {
+		// just the header
+		return w.Write(nil)
+	}
+	return nil
+}
+
+// outputIssue will write the issue in the 'output' format
+func (c *Cli) outputIssue(data interface{}) error {
+	w, err := c.newOutputWriter(os.Stdout)
+	if err != nil {
+		return err
+	}
+	return w.Write([]interface{}{data})
+}
@@ -0,0 +1,206 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/Netflix-Skunkworks/go-jira.v0/data"
+)
+
+// fieldCacheTTL is how long the field definitions from /rest/api/2/field are
+// cached for
+const fieldCacheTTL = 24 * time.Hour
+
+// Fields will return the field definitions from /rest/api/2/field.  They are
+// cached under ~/.jira.d/cache for each jira endpoint since they rarely
+// change.
+func (c *Cli) Fields() ([]interface{}, error) {
+	cacheFile := filepath.Join(homedir(), ".jira.d", "cache", fmt.Sprintf("fields-%s.json", c.endpoint.Host))
+	if stat, err := os.Stat(cacheFile); err == nil && time.Since(stat.ModTime()) < fieldCacheTTL {
+		if content, err := ioutil.ReadFile(cacheFile); err == nil {
+			fields := []interface{}{}
+			if err := json.Unmarshal(content, &fields); err == nil {
+				return fields, nil
+			}
+		}
+		log.Debugf("Ignoring invalid field cache %s", cacheFile)
+	}
+
+	uri := fmt.Sprintf("%s/rest/api/2/field", c.endpoint)
+	data, err := responseToJSON(c.get(uri))
+	if err != nil {
+		return nil, err
+	}
+	fields, _ := data.([]interface{})
+	if err := mkdir(filepath.Dir(cacheFile)); err == nil {
+		if content, err := jsonEncode(fields); err == nil {
+			if err := ioutil.WriteFile(cacheFile, []byte(content), 0600); err != nil {
+				log.Warningf("Failed to write %s: %s", cacheFile, err)
+			}
+		}
+	}
+	return fields, nil
+}
+
+// resolveField will return the field id for a field id or display name, or
+// an empty string if there is no such field
+func (c *Cli) resolveField(name string, metaFields jiradata.FieldMetaMap) string {
+	if _, ok := metaFields[name]; ok {
+		return name
+	}
+	for id, fieldMeta := range metaFields {
+		if fieldMeta != nil && strings.EqualFold(fieldMeta.Name, name) {
+			return id
+		}
+	}
+	fields, err := c.Fields()
+	if err != nil {
+		return ""
+	}
+	for _, f := range fields {
+		field, _ := f.(map[string]interface{})
+		id, _ := field["id"].(string)
+		if id == name {
+			return id
+		}
+		if fieldName, ok := field["name"].(string); ok && strings.EqualFold(fieldName, name) {
+			return id
+		}
+	}
+	return ""
+}
+
+// applyFieldOverrides will add the fields named by the -o/--override options
+// to the edited document, so any field can be set from the command line even
+// when the template does not mention it.  Overrides are given by field id or
+// display name, eg:
+//
+//	-o "Story Points=5"
+//	-o fixVersions=1.2
+//	-o labels+=foo
+//	-o labels-=bar
+//
+// KEY=VAL sets the field, KEY+=VAL and KEY-=VAL add to and remove from the
+// field with the "add" and "remove" update operations, they may be repeated
+// and are then a list of values.  Overrides that are not
+// fields are only template variables and are ignored.  When the document was
+// edited, rendered is the document before editing, and the overrides are only
+// applied to the fields that were not changed in the editor.
+func (c *Cli) applyFieldOverrides(edited map[string]interface{}, rendered map[string]interface{}, metaFields jiradata.FieldMetaMap) []string {
+	problems := []string{}
+	overrides, _ := c.opts["override"].(map[string]interface{})
+	if len(overrides) == 0 {
+		return problems
+	}
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := overrides[key]
+		name := key
+		operation := "set"
+		if strings.HasSuffix(name, "+") {
+			name, operation = strings.TrimSuffix(name, "+"), "add"
+		} else if strings.HasSuffix(name, "-") {
+			name, operation = strings.TrimSuffix(name, "-"), "remove"
+		}
+		name = strings.TrimSpace(name)
+
+		id := c.resolveField(name, metaFields)
+		if id == "" {
+			log.Debugf("Override %s is not a field, only using it for templates", name)
+			continue
+		}
+		fieldMeta, ok := metaFields[id]
+		if !ok || fieldMeta == nil {
+			if operation != "set" || id != name {
+				problems = append(problems, fmt.Sprintf("Field %s is not editable", fieldDisplayName(id, &jiradata.FieldMeta{Name: name})))
+			} else {
+				log.Debugf("Override %s is not editable here, only using it for templates", name)
+			}
+			continue
+		}
+
+		if rendered != nil && changedField(edited, rendered, id, name, fieldMeta.Name) {
+			log.Debugf("%s was changed in the editor, ignoring the override", name)
+			continue
+		}
+
+		fields, ok := edited["fields"].(map[string]interface{})
+		if !ok {
+			fields = map[string]interface{}{}
+			edited["fields"] = fields
+		}
+		if operation == "set" {
+			fields[id] = value
+			continue
+		}
+
+		if len(fieldMeta.Operations) > 0 && !hasOperation(fieldMeta.Operations, operation) {
+			problems = append(problems, fmt.Sprintf("Operation %s not allowed for %s, allowed operations: %s", operation, fieldDisplayName(id, fieldMeta), strings.Join(fieldMeta.Operations, ", ")))
+			continue
+		}
+		// a repeated KEY+=VAL or KEY-=VAL is a list of values, each one is
+		// its own operation
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+		items := []interface{}{}
+		for _, value := range values {
+			item := value
+			if fieldMeta.Schema != nil && fieldMeta.Schema.Type == "array" {
+				coerced, err := coerceScalar(value, fieldMeta.Schema.Items)
+				if err != nil {
+					problems = append(problems, fmt.Sprintf("Invalid value %q for %s: %s", fmt.Sprintf("%v", value), fieldDisplayName(id, fieldMeta), err))
+					continue
+				}
+				item = coerced
+			}
+			items = append(items, item)
+		}
+		if len(items) == 0 {
+			continue
+		}
+		// jira refuses a field that is both set and updated, the
+		// template value is dropped so only the update is applied
+		delete(fields, id)
+		update, ok := edited["update"].(map[string]interface{})
+		if !ok {
+			update = map[string]interface{}{}
+			edited["update"] = update
+		}
+		ops, _ := update[id].([]interface{})
+		for _, item := range items {
+			ops = append(ops, map[string]interface{}{operation: item})
+		}
+		update[id] = ops
+	}
+	return problems
+}
+
+// changedField will return true when the field, by any of the keys it may be
+// written as, was set or updated differently in the edited document than in
+// the rendered one
+func changedField(edited map[string]interface{}, rendered map[string]interface{}, keys ...string) bool {
+	for _, section := range []string{"fields", "update"} {
+		editedSection, _ := edited[section].(map[string]interface{})
+		renderedSection, _ := rendered[section].(map[string]interface{})
+		for _, key := range keys {
+			if key != "" && !reflect.DeepEqual(editedSection[key], renderedSection[key]) {
+				return true
+			}
+		}
+	}
+	return false
+}
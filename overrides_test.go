@@ -0,0 +1,110 @@
+package jira
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/Netflix-Skunkworks/go-jira.v0/data"
+)
+
+func testOverrideMeta() jiradata.FieldMetaMap {
+	return jiradata.FieldMetaMap{
+		"summary":  {Name: "Summary", Schema: &jiradata.JSONType{Type: "string"}},
+		"priority": {Name: "Priority", Schema: &jiradata.JSONType{Type: "priority"}},
+		"labels":   {Name: "Labels", Schema: &jiradata.JSONType{Type: "array", Items: "string"}, Operations: []string{"add", "remove", "set"}},
+	}
+}
+
+func TestApplyFieldOverrides(t *testing.T) {
+	c := &Cli{opts: map[string]interface{}{
+		"override": map[string]interface{}{
+			"summary": "foo",
+			"labels+": "bar",
+		},
+	}}
+	edited := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"summary": "",
+		},
+	}
+	if problems := c.applyFieldOverrides(edited, nil, testOverrideMeta()); len(problems) > 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	expected := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"summary": "foo",
+		},
+		"update": map[string]interface{}{
+			"labels": []interface{}{map[string]interface{}{"add": "bar"}},
+		},
+	}
+	if !reflect.DeepEqual(edited, expected) {
+		t.Errorf("expected:\n%#v\ngot:\n%#v", expected, edited)
+	}
+}
+
+func TestApplyFieldOverridesRepeated(t *testing.T) {
+	// -o labels+=a -o labels+=b -o labels-=c
+	c := &Cli{opts: map[string]interface{}{
+		"override": map[string]interface{}{
+			"labels+": []interface{}{"a", "b"},
+			"labels-": []interface{}{"c"},
+		},
+	}}
+	edited := map[string]interface{}{}
+	if problems := c.applyFieldOverrides(edited, nil, testOverrideMeta()); len(problems) > 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	expected := map[string]interface{}{
+		"fields": map[string]interface{}{},
+		"update": map[string]interface{}{
+			"labels": []interface{}{
+				map[string]interface{}{"add": "a"},
+				map[string]interface{}{"add": "b"},
+				map[string]interface{}{"remove": "c"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(edited, expected) {
+		t.Errorf("expected:\n%#v\ngot:\n%#v", expected, edited)
+	}
+}
+
+func TestApplyFieldOverridesEdited(t *testing.T) {
+	c := &Cli{opts: map[string]interface{}{
+		"override": map[string]interface{}{
+			"summary":  "foo",
+			"priority": "Bogus",
+			"labels+":  "bar",
+		},
+	}}
+	rendered := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"summary":  "foo",
+			"priority": map[string]interface{}{"name": "Bogus"},
+		},
+	}
+	// the summary and priority were changed in the editor, and the
+	// labels were not touched
+	edited := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"summary":  "bar",
+			"Priority": map[string]interface{}{"name": "High"},
+		},
+	}
+	if problems := c.applyFieldOverrides(edited, rendered, testOverrideMeta()); len(problems) > 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	expected := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"summary":  "bar",
+			"Priority": map[string]interface{}{"name": "High"},
+		},
+		"update": map[string]interface{}{
+			"labels": []interface{}{map[string]interface{}{"add": "bar"}},
+		},
+	}
+	if !reflect.DeepEqual(edited, expected) {
+		t.Errorf("expected:\n%#v\ngot:\n%#v", expected, edited)
+	}
+}
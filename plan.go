@@ -0,0 +1,579 @@
+package jira
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"gopkg.in/Netflix-Skunkworks/go-jira.v0/data"
+	"gopkg.in/coryb/yaml.v2"
+)
+
+// SyncIssue is an issue in the desired state file used by "plan" and
+// "apply".  The ID is a stable external id that is stored on the issue as a
+// label (with the 'syncLabelPrefix' option, default "sync:") so the issue
+// can be found again.  Parent and the Issue of the links are either the
+// ID of another entry or an issue key.
+type SyncIssue struct {
+	ID        string                 `json:"id" yaml:"id"`
+	Project   string                 `json:"project,omitempty" yaml:"project,omitempty"`
+	IssueType string                 `json:"issuetype,omitempty" yaml:"issuetype,omitempty"`
+	Parent    string                 `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Status    string                 `json:"status,omitempty" yaml:"status,omitempty"`
+	Fields    map[string]interface{} `json:"fields,omitempty" yaml:"fields,omitempty"`
+	Links     []SyncLink             `json:"links,omitempty" yaml:"links,omitempty"`
+}
+
+// SyncLink is a link from the issue, like {type: blocks, issue: other}
+// meaning "this issue blocks other"
+type SyncLink struct {
+	Type  string `json:"type" yaml:"type"`
+	Issue string `json:"issue" yaml:"issue"`
+}
+
+// syncChange is the planned change for a SyncIssue
+type syncChange struct {
+	issue  *SyncIssue
+	key    string                 // the live issue, empty when creating
+	parent string                 // the id of a parent created by the same apply
+	doc    map[string]interface{} // the fields to create or update
+	diffs  []string
+	status string // the status to transition to, empty for none
+	from   string
+	links  []syncLinkChange
+}
+
+type syncLinkChange struct {
+	link     SyncLink
+	linkType *IssueLinkType
+	reverse  bool
+}
+
+// readSyncIssues will read the desired state file, either a list of issues
+// or a document with default 'project' and 'issuetype' and a list of
+// 'issues'
+func readSyncIssues(file string) ([]*SyncIssue, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read %s: %s", file, err)
+	}
+	var raw interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("Failed to parse YAML from %s: %s", file, err)
+	}
+	fixed, err := yamlFixup(raw)
+	if err != nil {
+		return nil, err
+	}
+	var defaults SyncIssue
+	list := fixed
+	if document, ok := fixed.(map[string]interface{}); ok {
+		defaults.Project, _ = document["project"].(string)
+		defaults.IssueType, _ = document["issuetype"].(string)
+		list = document["issues"]
+	}
+	encoded, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+	issues := []*SyncIssue{}
+	if err := json.Unmarshal(encoded, &issues); err != nil {
+		return nil, fmt.Errorf("Invalid issues in %s: %s", file, err)
+	}
+	seen := map[string]bool{}
+	for i, issue := range issues {
+		if issue.ID == "" || strings.ContainsAny(issue.ID, " \t\n") {
+			return nil, fmt.Errorf("Issue %d in %s needs an id without spaces", i+1, file)
+		}
+		if seen[issue.ID] {
+			return nil, fmt.Errorf("Duplicate id %s in %s", issue.ID, file)
+		}
+		seen[issue.ID] = true
+		if issue.Project == "" {
+			issue.Project = defaults.Project
+		}
+		if issue.IssueType == "" {
+			issue.IssueType = defaults.IssueType
+		}
+		if issue.Fields == nil {
+			issue.Fields = map[string]interface{}{}
+		}
+	}
+	return issues, nil
+}
+
+func (c *Cli) syncLabel(id string) string {
+	return c.GetOptString("syncLabelPrefix", "sync:") + id
+}
+
+// syncLiveIssues will find the live issues labeled with the external ids
+func (c *Cli) syncLiveIssues(issues []*SyncIssue) (map[string]map[string]interface{}, error) {
+	live := map[string]map[string]interface{}{}
+	for start := 0; start < len(issues); start += 50 {
+		end := start + 50
+		if end > len(issues) {
+			end = len(issues)
+		}
+		labels := []JQLValue{}
+		for _, issue := range issues[start:end] {
+			labels = append(labels, JQLString(c.syncLabel(issue.ID)))
+		}
+		query := JQLIn("labels", labels...).String()
+		data, err := c.search(query, []string{"*all"}, 0, 1000)
+		if err != nil {
+			return nil, err
+		}
+		results, _ := data.(map[string]interface{})
+		found, _ := results["issues"].([]interface{})
+		for _, i := range found {
+			issueData, _ := i.(map[string]interface{})
+			fields, _ := issueData["fields"].(map[string]interface{})
+			issueLabels, _ := fields["labels"].([]interface{})
+			for _, issue := range issues[start:end] {
+				if !hasLabel(issueLabels, c.syncLabel(issue.ID)) {
+					continue
+				}
+				if other, ok := live[issue.ID]; ok {
+					err := fmt.Errorf("External id %s is on both %s and %s", issue.ID, other["key"], issueData["key"])
+					log.Errorf("%s", err)
+					return nil, err
+				}
+				live[issue.ID] = issueData
+			}
+		}
+	}
+	return live, nil
+}
+
+func hasLabel(labels []interface{}, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// syncEqual will return true if the live value already has the desired
+// value.  Only the properties of objects in the desired value are compared
+// (eg {name: High} matches the live priority) and lists are compared
+// ignoring order.
+func syncEqual(desired interface{}, live interface{}) bool {
+	if isEmptyValue(desired) || isEmptyValue(identifyingEmpty(desired)) {
+		return isEmptyValue(live)
+	}
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range d {
+			if !syncEqual(value, l[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(d) {
+			return false
+		}
+		for _, item := range d {
+			found := false
+			for _, liveItem := range l {
+				if syncEqual(item, liveItem) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+	return formatValue(desired) == formatValue(live)
+}
+
+// syncPlan will compare the desired issues with the live issues and return
+// the changes needed, along with the live issues by external id
+func (c *Cli) syncPlan(issues []*SyncIssue) ([]*syncChange, map[string]map[string]interface{}, error) {
+	live, err := c.syncLiveIssues(issues)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := map[string]*SyncIssue{}
+	for _, issue := range issues {
+		byID[issue.ID] = issue
+	}
+	// the key of an entry, or a placeholder for issues not created yet
+	keyOf := func(ref string) string {
+		if _, ok := byID[ref]; !ok {
+			return ref
+		}
+		if issueData, ok := live[ref]; ok {
+			return fmt.Sprintf("%v", issueData["key"])
+		}
+		return fmt.Sprintf("(%s)", ref)
+	}
+
+	changes := []*syncChange{}
+	problems := []string{}
+	createMetas := map[string]map[string]interface{}{}
+	for _, issue := range issues {
+		change := &syncChange{issue: issue}
+		fields := map[string]interface{}{}
+		for name, value := range issue.Fields {
+			fields[name] = value
+		}
+		issueData, exists := live[issue.ID]
+
+		var meta map[string]interface{}
+		if !exists {
+			if issue.Project == "" || issue.IssueType == "" {
+				problems = append(problems, fmt.Sprintf("%s: missing project or issuetype", issue.ID))
+				continue
+			}
+			metaKey := fmt.Sprintf("%s/%s", issue.Project, issue.IssueType)
+			if meta = createMetas[metaKey]; meta == nil {
+				data, err := c.createIssueMetaData(issue.Project, issue.IssueType)
+				if err != nil {
+					return nil, nil, err
+				}
+				meta, _ = data.(map[string]interface{})
+				createMetas[metaKey] = meta
+			}
+			fields["project"] = map[string]interface{}{"key": issue.Project}
+			fields["issuetype"] = map[string]interface{}{"name": issue.IssueType}
+			if issue.Parent != "" {
+				fields["parent"] = map[string]interface{}{"key": keyOf(issue.Parent)}
+				if _, ok := byID[issue.Parent]; ok && live[issue.Parent] == nil {
+					change.parent = issue.Parent
+				}
+			}
+		} else {
+			change.key = fmt.Sprintf("%v", issueData["key"])
+			uri := fmt.Sprintf("%s/rest/api/2/issue/%s/editmeta", c.endpoint, change.key)
+			data, err := responseToJSON(c.get(uri))
+			if err != nil {
+				return nil, nil, err
+			}
+			meta, _ = data.(map[string]interface{})
+		}
+		metaFields := fieldMetaMap(meta)
+
+		doc := map[string]interface{}{"fields": fields}
+		issueProblems := coerceFields(doc, metaFields)
+		// the external id label is kept when the labels are managed
+		if labels, ok := fields["labels"].([]interface{}); ok || !exists {
+			if !hasLabel(labels, c.syncLabel(issue.ID)) {
+				labels = append(labels, c.syncLabel(issue.ID))
+			}
+			fields["labels"] = labels
+		}
+		if exists {
+			liveFields, _ := issueData["fields"].(map[string]interface{})
+			changed := map[string]interface{}{}
+			for name, value := range fields {
+				if !syncEqual(value, liveFields[name]) {
+					changed[name] = value
+					change.diffs = append(change.diffs, fmt.Sprintf("%s: %q -> %q", fieldDisplayName(name, metaFields[name]), formatValue(liveFields[name]), formatValue(value)))
+				}
+			}
+			doc["fields"] = changed
+			if current := issueField(issueData, "status", "name"); issue.Status != "" && !syncStatusMatches(issueData, issue.Status) {
+				change.status, change.from = issue.Status, current
+			}
+		} else {
+			for name, value := range fields {
+				change.diffs = append(change.diffs, fmt.Sprintf("%s: %q", fieldDisplayName(name, metaFields[name]), formatValue(value)))
+			}
+			change.status = issue.Status
+		}
+		sort.Strings(change.diffs)
+		issueProblems = append(issueProblems, validateEdit(doc, metaFields)...)
+		for _, problem := range issueProblems {
+			problems = append(problems, fmt.Sprintf("%s: %s", issue.ID, problem))
+		}
+		change.doc = doc
+
+		var liveLinks []interface{}
+		if exists {
+			liveFields, _ := issueData["fields"].(map[string]interface{})
+			liveLinks, _ = liveFields["issuelinks"].([]interface{})
+		}
+		for _, link := range issue.Links {
+			linkType, reverse, err := c.FindIssueLinkType(link.Type)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !syncHasLink(liveLinks, linkType.Name, reverse, keyOf(link.Issue)) {
+				change.links = append(change.links, syncLinkChange{link: link, linkType: linkType, reverse: reverse})
+			}
+		}
+
+		if !exists || len(change.diffs) > 0 || change.status != "" || len(change.links) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			log.Errorf("%s", problem)
+		}
+		err := fmt.Errorf("Found %d problems in the desired issues", len(problems))
+		return nil, nil, err
+	}
+	changes, err = syncOrder(changes)
+	return changes, live, err
+}
+
+// syncOrder will sort the changes so that issues to be created come after
+// the parent they reference, otherwise keeping the order of the file
+func syncOrder(changes []*syncChange) ([]*syncChange, error) {
+	created := map[string]*syncChange{}
+	for _, change := range changes {
+		if change.key == "" {
+			created[change.issue.ID] = change
+		}
+	}
+	ordered := make([]*syncChange, 0, len(changes))
+	done := map[*syncChange]bool{}
+	visiting := map[*syncChange]bool{}
+	var visit func(change *syncChange) error
+	visit = func(change *syncChange) error {
+		if done[change] {
+			return nil
+		}
+		if visiting[change] {
+			return fmt.Errorf("%s: the parent of the issue is its own descendant", change.issue.ID)
+		}
+		visiting[change] = true
+		if parent, ok := created[change.issue.Parent]; ok && change.key == "" {
+			if err := visit(parent); err != nil {
+				return err
+			}
+		}
+		visiting[change] = false
+		done[change] = true
+		ordered = append(ordered, change)
+		return nil
+	}
+	for _, change := range changes {
+		if err := visit(change); err != nil {
+			log.Errorf("%s", err)
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+func syncStatusMatches(issueData map[string]interface{}, target string) bool {
+	fields, _ := issueData["fields"].(map[string]interface{})
+	status, _ := fields["status"].(map[string]interface{})
+	current := &jiradata.Status{Name: fmt.Sprintf("%v", status["name"])}
+	if category, ok := status["statusCategory"].(map[string]interface{}); ok {
+		current.StatusCategory = &jiradata.StatusCategory{
+			Name: fmt.Sprintf("%v", category["name"]),
+			Key:  fmt.Sprintf("%v", category["key"]),
+		}
+	}
+	return statusMatches(current, target)
+}
+
+// syncHasLink will return true if the live links include a link of the type
+// to the other issue in the given direction
+func syncHasLink(links []interface{}, linkType string, reverse bool, other string) bool {
+	side := "outwardIssue"
+	if reverse {
+		side = "inwardIssue"
+	}
+	for _, l := range links {
+		link, _ := l.(map[string]interface{})
+		if issueType, _ := link["type"].(map[string]interface{}); issueType["name"] != linkType {
+			continue
+		}
+		if issue, ok := link[side].(map[string]interface{}); ok && issue["key"] == other {
+			return true
+		}
+	}
+	return false
+}
+
+// printSyncPlan will show the changes, returning false when there are none
+func (c *Cli) printSyncPlan(changes []*syncChange) bool {
+	if len(changes) == 0 {
+		fmt.Println("No changes, the issues match the desired state")
+		return false
+	}
+	creates, updates, transitions, links := 0, 0, 0, 0
+	for _, change := range changes {
+		name := change.issue.ID
+		if change.key == "" {
+			creates++
+			fmt.Printf("+ create %s (%s %s)\n", name, change.issue.Project, change.issue.IssueType)
+		} else {
+			name = fmt.Sprintf("%s %s", name, change.key)
+			if len(change.diffs) > 0 {
+				updates++
+				fmt.Printf("~ update %s\n", name)
+			}
+		}
+		for _, diff := range change.diffs {
+			fmt.Printf("    %s\n", diff)
+		}
+		if change.status != "" {
+			transitions++
+			if change.from == "" {
+				fmt.Printf("~ transition %s to %s\n", name, change.status)
+			} else {
+				fmt.Printf("~ transition %s: %s -> %s\n", name, change.from, change.status)
+			}
+		}
+		for _, link := range change.links {
+			links++
+			fmt.Printf("+ link %s %s %s\n", name, link.link.Type, link.link.Issue)
+		}
+	}
+	fmt.Printf("Plan: %d to create, %d to update, %d to transition, %d links to add\n", creates, updates, transitions, links)
+	return true
+}
+
+// CmdPlan will show the changes needed to make the issues match the desired
+// state file
+func (c *Cli) CmdPlan(file string) error {
+	log.Debugf("plan called")
+	issues, err := readSyncIssues(file)
+	if err != nil {
+		log.Errorf("%s", err)
+		return err
+	}
+	changes, _, err := c.syncPlan(issues)
+	if err != nil {
+		return err
+	}
+	c.printSyncPlan(changes)
+	return nil
+}
+
+// CmdApply will show the plan for the desired state file like CmdPlan, then
+// after confirmation (unless the 'yes' option is set) it will create and
+// update the issues, add the links and transition the issues to their
+// status.  Issues are found by their external id so running it again with
+// the same file will not duplicate anything.
+func (c *Cli) CmdApply(file string) error {
+	log.Debugf("apply called")
+	issues, err := readSyncIssues(file)
+	if err != nil {
+		log.Errorf("%s", err)
+		return err
+	}
+	changes, live, err := c.syncPlan(issues)
+	if err != nil {
+		return err
+	}
+	if !c.printSyncPlan(changes) {
+		return nil
+	}
+	if !c.getOptBool("yes", false) && !promptYN("Apply these changes?", false) {
+		err := fmt.Errorf("Apply cancelled")
+		log.Errorf("%s", err)
+		return err
+	}
+
+	keys := map[string]string{}
+	for id, issueData := range live {
+		keys[id] = fmt.Sprintf("%v", issueData["key"])
+	}
+	// ids of issues created earlier in the apply are replaced by the key
+	keyOf := func(ref string) string {
+		if key, ok := keys[ref]; ok {
+			return key
+		}
+		return ref
+	}
+
+	for _, change := range changes {
+		fields, _ := change.doc["fields"].(map[string]interface{})
+		if change.key == "" {
+			if change.parent != "" {
+				fields["parent"] = map[string]interface{}{"key": keyOf(change.parent)}
+			}
+			json, err := jsonEncode(change.doc)
+			if err != nil {
+				return err
+			}
+			key, err := c.createIssue(json)
+			if err != nil {
+				return err
+			}
+			if key == "" {
+				// dryrun
+				continue
+			}
+			change.key = key
+			keys[change.issue.ID] = key
+		} else if len(fields) > 0 {
+			if err := c.updateIssue(change.key, change.doc); err != nil {
+				return err
+			}
+		}
+		if !c.GetOptBool("quiet", false) {
+			fmt.Printf("OK %s %s/browse/%s\n", change.key, c.endpoint, change.key)
+		}
+	}
+
+	for _, change := range changes {
+		if change.key == "" {
+			continue
+		}
+		for _, link := range change.links {
+			inward, outward := change.key, keyOf(link.link.Issue)
+			if link.reverse {
+				inward, outward = outward, inward
+			}
+			if err := c.linkIssues(link.linkType.Name, inward, outward); err != nil {
+				return err
+			}
+		}
+		if change.status != "" {
+			quiet := c.GetOptBool("quiet", false)
+			c.opts["quiet"] = true
+			err := c.CmdMoveTo(change.key, change.status)
+			c.opts["quiet"] = quiet
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// updateIssue will PUT the changed fields of the issue
+func (c *Cli) updateIssue(issue string, doc map[string]interface{}) error {
+	json, err := jsonEncode(doc)
+	if err != nil {
+		return err
+	}
+	uri := fmt.Sprintf("%s/rest/api/2/issue/%s", c.endpoint, issue)
+	if c.getOptBool("dryrun", false) {
+		log.Debugf("PUT: %s", json)
+		log.Debugf("Dryrun mode, skipping PUT")
+		return nil
+	}
+	resp, err := c.put(uri, json)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 204 {
+		logBuffer := bytes.NewBuffer(make([]byte, 0))
+		resp.Write(logBuffer)
+		err := fmt.Errorf("Unexpected Response From PUT")
+		log.Errorf("%s:\n%s", err, logBuffer)
+		return err
+	}
+	return nil
+}
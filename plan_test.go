@@ -0,0 +1,144 @@
+package jira
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testSyncChange(id, parent, key string) *syncChange {
+	return &syncChange{issue: &SyncIssue{ID: id, Parent: parent}, key: key}
+}
+
+func TestSyncOrder(t *testing.T) {
+	changes := []*syncChange{
+		testSyncChange("subtask", "story", ""),
+		testSyncChange("story", "epic", ""),
+		testSyncChange("other", "GOJIRA-1", ""),
+		testSyncChange("epic", "", ""),
+		// existing issues keep their place
+		testSyncChange("updated", "epic", "GOJIRA-2"),
+	}
+	ordered, err := syncOrder(changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, change := range ordered {
+		ids = append(ids, change.issue.ID)
+	}
+	expected := []string{"epic", "story", "subtask", "other", "updated"}
+	if len(ids) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, ids)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, ids)
+		}
+	}
+}
+
+func TestSyncOrderCycle(t *testing.T) {
+	changes := []*syncChange{
+		testSyncChange("a", "b", ""),
+		testSyncChange("b", "a", ""),
+	}
+	if _, err := syncOrder(changes); err == nil {
+		t.Error("expected an error for a parent cycle")
+	}
+}
+
+func TestApplyUnchangedParent(t *testing.T) {
+	stringMeta := map[string]interface{}{"name": "Summary", "schema": map[string]interface{}{"type": "string"}}
+	server := newTestJira(t, map[string]testJiraHandler{
+		// the parent was created by an earlier apply and has not changed
+		"POST /rest/api/2/search": testJiraRespond(200, map[string]interface{}{
+			"total": 1,
+			"issues": []interface{}{
+				map[string]interface{}{
+					"key": "GOJIRA-1",
+					"fields": map[string]interface{}{
+						"summary": "epic",
+						"labels":  []interface{}{"sync:epic"},
+						"status":  map[string]interface{}{"name": "To Do"},
+					},
+				},
+			},
+		}),
+		"GET /rest/api/2/issue/GOJIRA-1/editmeta": testJiraRespond(200, map[string]interface{}{
+			"fields": map[string]interface{}{"summary": stringMeta},
+		}),
+		"GET /rest/api/2/issue/createmeta": testJiraRespond(200, map[string]interface{}{
+			"projects": []interface{}{map[string]interface{}{
+				"issuetypes": []interface{}{map[string]interface{}{
+					"fields": map[string]interface{}{
+						"summary":   stringMeta,
+						"project":   map[string]interface{}{"name": "Project", "schema": map[string]interface{}{"type": "project"}},
+						"issuetype": map[string]interface{}{"name": "Issue Type", "schema": map[string]interface{}{"type": "issuetype"}},
+						"parent":    map[string]interface{}{"name": "Parent", "schema": map[string]interface{}{"type": "issuelink"}},
+						"labels":    map[string]interface{}{"name": "Labels", "schema": map[string]interface{}{"type": "array", "items": "string"}},
+					},
+				}},
+			}},
+		}),
+		"GET /rest/api/2/issueLinkType": testJiraRespond(200, map[string]interface{}{
+			"issueLinkTypes": []interface{}{
+				map[string]interface{}{"name": "Blocks", "inward": "is blocked by", "outward": "blocks"},
+			},
+		}),
+		"POST /rest/api/2/issue":     testJiraRespond(201, map[string]interface{}{"key": "GOJIRA-2"}),
+		"POST /rest/api/2/issueLink": testJiraRespond(201, nil),
+	})
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "jira-apply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "desired.yml")
+	err = ioutil.WriteFile(file, []byte(`
+project: GOJIRA
+issuetype: Sub-task
+issues:
+  - id: epic
+    issuetype: Task
+    fields:
+      summary: epic
+  - id: child
+    parent: epic
+    fields:
+      summary: child
+    links:
+      - type: blocks
+        issue: epic
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := server.cli(map[string]interface{}{"yes": true, "quiet": true})
+	if err := c.CmdApply(file); err != nil {
+		t.Fatal(err)
+	}
+
+	created := server.sent("POST /rest/api/2/issue")
+	if len(created) != 1 {
+		t.Fatalf("expected 1 issue to be created, got %d", len(created))
+	}
+	fields := created[0].(map[string]interface{})["fields"].(map[string]interface{})
+	if parent := fields["parent"].(map[string]interface{})["key"]; parent != "GOJIRA-1" {
+		t.Errorf("expected the parent GOJIRA-1, got %v", parent)
+	}
+	links := server.sent("POST /rest/api/2/issueLink")
+	if len(links) != 1 {
+		t.Fatalf("expected 1 link, got %d", len(links))
+	}
+	link := links[0].(map[string]interface{})
+	inward := link["inwardIssue"].(map[string]interface{})["key"]
+	outward := link["outwardIssue"].(map[string]interface{})["key"]
+	if inward != "GOJIRA-2" || outward != "GOJIRA-1" {
+		t.Errorf("expected GOJIRA-2 blocks GOJIRA-1, got %v blocks %v", inward, outward)
+	}
+}
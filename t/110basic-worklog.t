@@ -9,7 +9,7 @@ if [ -n "$JIRACLOUD" ]; then
     ENDPOINT="https://go-jira.atlassian.net"
 fi
 
-PLAN 8
+PLAN 18
 
 # reset login
 RUNS $jira logout
@@ -39,10 +39,48 @@ EOF
 ###############################################################################
 ## Verify worklog got added to issue
 ###############################################################################
+worklog=$($jira worklog $issue | awk '/- id:/{print $3}')
 RUNS $jira worklog $issue
 DIFF <<EOF
-- # gojira, a minute ago
+- id: $worklog # gojira, a minute ago
   comment: work is hard
   timeSpent: 1h 12m
 
 EOF
+
+###############################################################################
+## Invalid time-spent values are rejected before sending to jira
+###############################################################################
+NRUNS $jira add worklog $issue --comment "work is hard" --time-spent "an hour" --noedit
+EDIFF <<EOF
+ERROR Invalid time-spent "an hour", expected a duration like "1h 30m" using w, d, h, m units
+ERROR Invalid time-spent "an hour", expected a duration like "1h 30m" using w, d, h, m units
+EOF
+
+###############################################################################
+## Edit the worklog, verify the update
+###############################################################################
+RUNS $jira worklog edit $issue $worklog --comment "work is easy" --time-spent "30m" --adjust-estimate leave --noedit
+DIFF <<EOF
+OK $issue $ENDPOINT/browse/$issue
+EOF
+
+RUNS $jira worklog $issue
+DIFF <<EOF
+- id: $worklog # gojira, a minute ago
+  comment: work is easy
+  timeSpent: 30m
+
+EOF
+
+###############################################################################
+## Remove the worklog
+###############################################################################
+RUNS $jira worklog rm $issue $worklog
+DIFF <<EOF
+OK $issue $ENDPOINT/browse/$issue
+EOF
+
+RUNS $jira worklog $issue
+DIFF <<EOF
+EOF
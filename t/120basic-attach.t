@@ -0,0 +1,74 @@
+#!/bin/bash
+eval "$(curl -q -s https://raw.githubusercontent.com/coryb/osht/master/osht.sh)"
+cd $(dirname $0)
+jira="../jira --project BASIC"
+export JIRA_LOG_FORMAT="%{level:-5s} %{message}"
+
+ENDPOINT="http://localhost:8080"
+if [ -n "$JIRACLOUD" ]; then
+    ENDPOINT="https://go-jira.atlassian.net"
+fi
+
+PLAN 14
+
+# reset login
+RUNS $jira logout
+RUNS $jira login
+
+# cleanup from previous failed test executions
+($jira ls | awk -F: '{print $1}' | while read issue; do ../jira done $issue; done) | sed 's/^/# CLEANUP: /g'
+
+echo "crash log" > crash.log
+echo "stack trace" > stack.log
+
+###############################################################################
+## Create an issue with an attachment
+###############################################################################
+RUNS $jira create -o summary=summary -o description=description --noedit --saveFile issue.props --attach crash.log
+issue=$(awk '/issue/{print $2}' issue.props)
+
+DIFF <<EOF
+OK $issue $ENDPOINT/browse/$issue
+EOF
+
+###############################################################################
+## Attach another file to the issue
+###############################################################################
+RUNS $jira attach $issue stack.log
+DIFF <<EOF
+OK $issue $ENDPOINT/browse/$issue
+EOF
+
+###############################################################################
+## List the attachments on the issue
+###############################################################################
+crash=$($jira attachments $issue | awk -F: '/crash.log/{print $1}')
+stack=$($jira attachments $issue | awk -F: '/stack.log/{print $1}')
+RUNS $jira attachments $issue
+DIFF <<EOF
+$(printf %-8s $crash:) crash.log (10B) # gojira, a minute ago
+$(printf %-8s $stack:) stack.log (12B) # gojira, a minute ago
+EOF
+
+###############################################################################
+## Download the attachment to stdout
+###############################################################################
+RUNS $jira attachment get $crash -O -
+DIFF <<EOF
+crash log
+EOF
+
+###############################################################################
+## Remove an attachment, then verify it is gone
+###############################################################################
+RUNS $jira attachment rm $stack
+DIFF <<EOF
+OK $stack
+EOF
+
+RUNS $jira attachments $issue
+DIFF <<EOF
+$(printf %-8s $crash:) crash.log (10B) # gojira, a minute ago
+EOF
+
+rm -f crash.log stack.log
@@ -0,0 +1,83 @@
+#!/bin/bash
+eval "$(curl -q -s https://raw.githubusercontent.com/coryb/osht/master/osht.sh)"
+cd $(dirname $0)
+jira="../jira --project BASIC"
+export JIRA_LOG_FORMAT="%{level:-5s} %{message}"
+
+ENDPOINT="http://localhost:8080"
+if [ -n "$JIRACLOUD" ]; then
+    ENDPOINT="https://go-jira.atlassian.net"
+fi
+
+PLAN 16
+
+# reset login
+RUNS $jira logout
+RUNS $jira login
+
+# cleanup from previous failed test executions
+($jira ls | awk -F: '{print $1}' | while read issue; do ../jira done $issue; done) | sed 's/^/# CLEANUP: /g'
+
+###############################################################################
+## Create an issue
+###############################################################################
+RUNS $jira create -o summary=summary -o description=description --noedit --saveFile issue.props
+issue=$(awk '/issue/{print $2}' issue.props)
+
+DIFF <<EOF
+OK $issue $ENDPOINT/browse/$issue
+EOF
+
+###############################################################################
+## Add a comment from a file and from stdin
+###############################################################################
+echo "comment from file" > comment.txt
+RUNS $jira comment $issue -m @comment.txt
+DIFF <<EOF
+OK $issue $ENDPOINT/browse/$issue
+EOF
+
+RUNS sh -c "echo 'comment from stdin' | $jira comment $issue -m -"
+DIFF <<EOF
+OK $issue $ENDPOINT/browse/$issue
+EOF
+
+###############################################################################
+## List the comments on the issue
+###############################################################################
+first=$($jira comments $issue | awk '/comment from file/{print id} /- id:/{id=$3}')
+second=$($jira comments $issue | awk '/comment from stdin/{print id} /- id:/{id=$3}')
+RUNS $jira comments $issue
+DIFF <<EOF
+- id: $first # gojira, a minute ago
+  body: |
+    comment from file
+
+- id: $second # gojira, a minute ago
+  body: |
+    comment from stdin
+
+EOF
+
+###############################################################################
+## Edit the first comment, then remove the second
+###############################################################################
+RUNS $jira comment edit $issue $first -m "edited comment"
+DIFF <<EOF
+OK $issue $ENDPOINT/browse/$issue
+EOF
+
+RUNS $jira comment rm $issue $second
+DIFF <<EOF
+OK $issue $ENDPOINT/browse/$issue
+EOF
+
+RUNS $jira comments $issue
+DIFF <<EOF
+- id: $first # gojira, a minute ago
+  body: |
+    edited comment
+
+EOF
+
+rm -f comment.txt
@@ -0,0 +1,67 @@
+#!/bin/bash
+eval "$(curl -q -s https://raw.githubusercontent.com/coryb/osht/master/osht.sh)"
+cd $(dirname $0)
+jira="../jira --project BASIC"
+export JIRA_LOG_FORMAT="%{level:-5s} %{message}"
+
+ENDPOINT="http://localhost:8080"
+if [ -n "$JIRACLOUD" ]; then
+    ENDPOINT="https://go-jira.atlassian.net"
+fi
+
+PLAN 20
+
+# reset login
+RUNS $jira logout
+RUNS $jira login
+
+# cleanup from previous failed test executions
+($jira ls | awk -F: '{print $1}' | while read issue; do ../jira done $issue; done) | sed 's/^/# CLEANUP: /g'
+
+###############################################################################
+## Create three issues to link
+###############################################################################
+RUNS $jira create -o summary="link one" --noedit
+one=$(awk '{print $2}' $OSHT_STDOUT)
+RUNS $jira create -o summary="link two" --noedit
+two=$(awk '{print $2}' $OSHT_STDOUT)
+RUNS $jira create -o summary="link three" --noedit
+three=$(awk '{print $2}' $OSHT_STDOUT)
+
+###############################################################################
+## Link types are found by name, or by their outward or inward description
+###############################################################################
+RUNS $jira issuelink $one Blocks $two
+RUNS $jira links $one
+DIFF <<EOF
+blocks:
+  $(printf %-12s $two) [To Do] link two
+EOF
+
+RUNS $jira issuelink $three "is blocked by" $one
+RUNS $jira links $three
+DIFF <<EOF
+is blocked by:
+  $(printf %-12s $one) [To Do] link one
+EOF
+
+###############################################################################
+## Unlink by link type description, and without a link type
+###############################################################################
+RUNS $jira unlink $two $one "is blocked by"
+RUNS $jira links $two
+DIFF <<EOF
+EOF
+
+RUNS $jira unlink $one $three
+RUNS $jira links $one
+DIFF <<EOF
+EOF
+
+NRUNS $jira unlink $one $two
+EDIFF <<EOF
+ERROR No links found between $one and $two
+ERROR No links found between $one and $two
+EOF
+
+NRUNS $jira issuelink $one "is not a link type" $two
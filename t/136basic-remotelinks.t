@@ -0,0 +1,74 @@
+#!/bin/bash
+eval "$(curl -q -s https://raw.githubusercontent.com/coryb/osht/master/osht.sh)"
+cd $(dirname $0)
+jira="../jira --project BASIC"
+export JIRA_LOG_FORMAT="%{level:-5s} %{message}"
+
+ENDPOINT="http://localhost:8080"
+if [ -n "$JIRACLOUD" ]; then
+    ENDPOINT="https://go-jira.atlassian.net"
+fi
+
+PLAN 19
+
+# reset login
+RUNS $jira logout
+RUNS $jira login
+
+# cleanup from previous failed test executions
+($jira ls | awk -F: '{print $1}' | while read issue; do ../jira done $issue; done) | sed 's/^/# CLEANUP: /g'
+
+###############################################################################
+## Create an issue to add remote links to
+###############################################################################
+RUNS $jira create -o summary="remote links" --noedit
+issue=$(awk '{print $2}' $OSHT_STDOUT)
+
+###############################################################################
+## Add a remote link
+###############################################################################
+RUNS $jira remotelink add $issue http://example.com/builds/1 --title "Build 1"
+DIFF <<EOF
+OK $issue $ENDPOINT/browse/$issue
+EOF
+
+RUNS $jira remotelinks $issue
+id=$(awk -F: '{print $1}' $OSHT_STDOUT)
+DIFF <<EOF
+$(printf %-8s $id:) Build 1 http://example.com/builds/1
+EOF
+
+###############################################################################
+## Adding the same url again updates the link instead of adding another
+###############################################################################
+RUNS $jira remotelink add $issue http://example.com/builds/1 --title "Build 1 passed"
+RUNS $jira remotelinks $issue
+DIFF <<EOF
+$(printf %-8s $id:) Build 1 passed http://example.com/builds/1
+EOF
+
+###############################################################################
+## View only fetches the remote links with the remotelinks expansion
+###############################################################################
+RUNS $jira view $issue -x remotelinks
+GREP "^  - Build 1 passed: http://example.com/builds/1$"
+
+RUNS $jira view $issue
+NGREP "http://example.com/builds/1"
+
+###############################################################################
+## Remove the remote link
+###############################################################################
+RUNS $jira remotelink rm $issue $id
+DIFF <<EOF
+OK $issue $ENDPOINT/browse/$issue
+EOF
+
+RUNS $jira remotelinks $issue
+DIFF <<EOF
+EOF
+
+###############################################################################
+## Close the issue
+###############################################################################
+RUNS $jira done $issue
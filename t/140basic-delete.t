@@ -0,0 +1,84 @@
+#!/bin/bash
+eval "$(curl -q -s https://raw.githubusercontent.com/coryb/osht/master/osht.sh)"
+cd $(dirname $0)
+jira="../jira --project BASIC"
+export JIRA_LOG_FORMAT="%{level:-5s} %{message}"
+
+ENDPOINT="http://localhost:8080"
+if [ -n "$JIRACLOUD" ]; then
+    ENDPOINT="https://go-jira.atlassian.net"
+fi
+
+PLAN 18
+
+# reset login
+RUNS $jira logout
+RUNS $jira login
+
+# cleanup from previous failed test executions
+($jira ls | awk -F: '{print $1}' | while read issue; do ../jira done $issue; done) | sed 's/^/# CLEANUP: /g'
+
+###############################################################################
+## Create an issue with a subtask, and another issue to delete by query
+###############################################################################
+RUNS $jira create -o summary=parent -o description=description --noedit --saveFile issue.props
+issue=$(awk '/issue/{print $2}' issue.props)
+
+DIFF <<EOF
+OK $issue $ENDPOINT/browse/$issue
+EOF
+
+RUNS $jira subtask $issue -o summary=child -o description=description --noedit --saveFile subtask.props
+subtask=$(awk '/issue/{print $2}' subtask.props)
+
+DIFF <<EOF
+OK $subtask $ENDPOINT/browse/$subtask
+EOF
+
+RUNS $jira create -o summary=deleteme -o description=description --noedit --saveFile query.props
+other=$(awk '/issue/{print $2}' query.props)
+
+DIFF <<EOF
+OK $other $ENDPOINT/browse/$other
+EOF
+
+###############################################################################
+## Declining the confirmation does not delete anything
+###############################################################################
+NRUNS sh -c "echo n | $jira delete $issue --subtasks"
+EDIFF <<EOF
+ERROR Delete cancelled
+ERROR Delete cancelled
+EOF
+
+###############################################################################
+## Issues with subtasks are only deleted with --subtasks
+###############################################################################
+NRUNS $jira delete $issue --yes
+EDIFF <<EOF
+ERROR Issue $issue has 1 subtasks, use --subtasks to delete them as well
+ERROR Issue $issue has 1 subtasks, use --subtasks to delete them as well
+EOF
+
+RUNS $jira delete $issue --subtasks --yes
+DIFF <<EOF
+OK $issue
+EOF
+
+###############################################################################
+## A query from the config files is never enough to delete issues
+###############################################################################
+NRUNS $jira --profile everything delete --yes
+EDIFF <<EOF
+ERROR Missing required arguments, either ISSUE or -q JQL are required
+ERROR Missing required arguments, either ISSUE or -q JQL are required
+EOF
+
+###############################################################################
+## Delete every issue matching a query
+###############################################################################
+RUNS $jira delete -q "project = BASIC AND summary ~ deleteme" --yes
+DIFF <<EOF
+Query matched 1 issues
+OK $other
+EOF
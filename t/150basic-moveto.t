@@ -0,0 +1,47 @@
+#!/bin/bash
+eval "$(curl -q -s https://raw.githubusercontent.com/coryb/osht/master/osht.sh)"
+cd $(dirname $0)
+jira="../jira --project BASIC"
+export JIRA_LOG_FORMAT="%{level:-5s} %{message}"
+
+ENDPOINT="http://localhost:8080"
+if [ -n "$JIRACLOUD" ]; then
+    ENDPOINT="https://go-jira.atlassian.net"
+fi
+
+PLAN 8
+
+# reset login
+RUNS $jira logout
+RUNS $jira login
+
+# cleanup from previous failed test executions
+($jira ls | awk -F: '{print $1}' | while read issue; do ../jira done $issue; done) | sed 's/^/# CLEANUP: /g'
+
+###############################################################################
+## Create an issue
+###############################################################################
+RUNS $jira create -o summary=summary -o description=description --noedit --saveFile issue.props
+issue=$(awk '/issue/{print $2}' issue.props)
+
+DIFF <<EOF
+OK $issue $ENDPOINT/browse/$issue
+EOF
+
+###############################################################################
+## Move the issue to a status, showing the planned path
+###############################################################################
+RUNS $jira move-to $issue "in progress"
+DIFF <<EOF
+$issue: To Do -[In Progress]-> In Progress
+OK $issue $ENDPOINT/browse/$issue
+EOF
+
+###############################################################################
+## Move the issue to a status category
+###############################################################################
+RUNS $jira move-to $issue done
+DIFF <<EOF
+$issue: In Progress -[Done]-> Done
+OK $issue $ENDPOINT/browse/$issue
+EOF
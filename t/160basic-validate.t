@@ -0,0 +1,51 @@
+#!/bin/bash
+eval "$(curl -q -s https://raw.githubusercontent.com/coryb/osht/master/osht.sh)"
+cd $(dirname $0)
+jira="../jira --project BASIC"
+export JIRA_LOG_FORMAT="%{level:-5s} %{message}"
+
+ENDPOINT="http://localhost:8080"
+if [ -n "$JIRACLOUD" ]; then
+    ENDPOINT="https://go-jira.atlassian.net"
+fi
+
+PLAN 12
+
+# reset login
+RUNS $jira logout
+RUNS $jira login
+
+###############################################################################
+## Every invalid field is reported before anything is sent to jira
+###############################################################################
+NRUNS $jira create -o priority=Bogus --noedit
+EDIFF <<EOF
+ERROR Invalid value "Bogus" for Priority (priority), allowed: Highest, High, Medium, Low, Lowest
+ERROR Summary (summary) is required
+ERROR Found 2 invalid fields
+EOF
+
+###############################################################################
+## Changes made in the editor win over the -o overrides
+###############################################################################
+RUNS $jira create -o summary=from-override --editor "sed -i s/from-override/from-editor/"
+edited=$(awk '{print $2}' $OSHT_STDOUT)
+RUNS $jira ls -q "key = $edited" --output csv --columns key,summary
+DIFF <<EOF
+key,summary
+$edited,from-editor
+EOF
+
+###############################################################################
+## An invalid override can be fixed in the editor
+###############################################################################
+RUNS $jira create -o summary=fixed -o priority=Bogus --editor "sed -i s/Bogus/High/"
+issue=$(awk '{print $2}' $OSHT_STDOUT)
+RUNS $jira ls -q "key = $issue" --output csv --columns key,summary,priority
+DIFF <<EOF
+key,summary,priority
+$issue,fixed,High
+EOF
+
+RUNS $jira done $edited
+RUNS $jira done $issue
@@ -0,0 +1,80 @@
+#!/bin/bash
+eval "$(curl -q -s https://raw.githubusercontent.com/coryb/osht/master/osht.sh)"
+cd $(dirname $0)
+jira="../jira --project BASIC"
+export JIRA_LOG_FORMAT="%{level:-5s} %{message}"
+
+ENDPOINT="http://localhost:8080"
+if [ -n "$JIRACLOUD" ]; then
+    ENDPOINT="https://go-jira.atlassian.net"
+fi
+
+PLAN 12
+
+# reset login
+RUNS $jira logout
+RUNS $jira login
+
+# cleanup from previous failed test executions
+($jira ls | awk -F: '{print $1}' | while read issue; do ../jira done $issue; done) | sed 's/^/# CLEANUP: /g'
+
+###############################################################################
+## Create an issue from a JSON document on stdin
+###############################################################################
+echo '{"fields":{"summary":"from stdin","description":"piped"}}' > stdin.json
+RUNS $jira create --file - --saveFile issue.props < stdin.json
+rm -f stdin.json
+issue=$(awk '/issue/{print $2}' issue.props)
+
+DIFF <<EOF
+OK $issue $ENDPOINT/browse/$issue
+EOF
+
+RUNS $jira view $issue
+DIFF <<EOF
+issue: $issue
+created: a minute ago
+status: To Do
+summary: from stdin
+project: BASIC
+issuetype: Bug
+assignee: gojira
+reporter: gojira
+priority: Medium
+votes: 0
+description: |
+  piped
+EOF
+
+###############################################################################
+## Edit the issue from a YAML file
+###############################################################################
+cat > edit.yml <<YML
+fields:
+  summary: from a file
+YML
+RUNS $jira edit $issue --file edit.yml
+rm -f edit.yml
+DIFF <<EOF
+OK $issue $ENDPOINT/browse/$issue
+EOF
+
+RUNS $jira ls
+DIFF <<EOF
+$(printf %-12s $issue:) from a file
+EOF
+
+###############################################################################
+## Documents are validated like edited templates
+###############################################################################
+cat > bad.yml <<YML
+fields:
+  priority: Bogus
+  summary: bad
+YML
+NRUNS $jira create --file bad.yml
+rm -f bad.yml
+EDIFF <<EOF
+ERROR Invalid value "Bogus" for Priority (priority), allowed: Highest, High, Medium, Low, Lowest
+ERROR Found 1 invalid fields
+EOF
@@ -0,0 +1,50 @@
+#!/bin/bash
+eval "$(curl -q -s https://raw.githubusercontent.com/coryb/osht/master/osht.sh)"
+cd $(dirname $0)
+jira="../jira --project BASIC"
+export JIRA_LOG_FORMAT="%{level:-5s} %{message}"
+
+ENDPOINT="http://localhost:8080"
+if [ -n "$JIRACLOUD" ]; then
+    ENDPOINT="https://go-jira.atlassian.net"
+fi
+
+PLAN 7
+
+# reset login
+RUNS $jira logout
+RUNS $jira login
+
+# cleanup from previous failed test executions
+($jira ls | awk -F: '{print $1}' | while read issue; do ../jira done $issue; done) | sed 's/^/# CLEANUP: /g'
+
+###############################################################################
+## Import issues from CSV, the invalid row is written to the failure report
+###############################################################################
+cat > import.csv <<CSV
+id,Summary,Priority
+one,first import,High
+two,second import,Bogus
+CSV
+NRUNS $jira import import.csv --concurrency 1
+issue=$(awk '/^one:/{print $2}' $OSHT_STDOUT)
+DIFF <<EOF
+one: $issue $ENDPOINT/browse/$issue
+EOF
+EDIFF <<EOF
+ERROR Row two: Invalid value "Bogus" for Priority (priority), allowed: Highest, High, Medium, Low, Lowest
+ERROR Failed to import 1 of 2 issues, import import.failed.yml to retry them
+ERROR Failed to import 1 of 2 issues, import import.failed.yml to retry them
+EOF
+
+###############################################################################
+## Fix the failure report and import it again
+###############################################################################
+sed -i.bak 's/Bogus/Low/; /^# ERROR/d' import.failed.yml
+RUNS $jira import import.failed.yml
+issue=$(awk '/^two:/{print $2}' $OSHT_STDOUT)
+DIFF <<EOF
+two: $issue $ENDPOINT/browse/$issue
+EOF
+rm -f import.csv import.failed.yml import.failed.yml.bak
+
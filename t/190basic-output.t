@@ -0,0 +1,57 @@
+#!/bin/bash
+eval "$(curl -q -s https://raw.githubusercontent.com/coryb/osht/master/osht.sh)"
+cd $(dirname $0)
+jira="../jira --project BASIC"
+export JIRA_LOG_FORMAT="%{level:-5s} %{message}"
+
+ENDPOINT="http://localhost:8080"
+if [ -n "$JIRACLOUD" ]; then
+    ENDPOINT="https://go-jira.atlassian.net"
+fi
+
+PLAN 12
+
+# reset login
+RUNS $jira logout
+RUNS $jira login
+
+# cleanup from previous failed test executions
+($jira ls | awk -F: '{print $1}' | while read issue; do ../jira done $issue; done) | sed 's/^/# CLEANUP: /g'
+
+###############################################################################
+## Create an issue with a summary that needs escaping
+###############################################################################
+RUNS $jira create -o summary='commas, "quotes" | pipes' --noedit --saveFile issue.props
+issue=$(awk '/issue/{print $2}' issue.props)
+
+DIFF <<EOF
+OK $issue $ENDPOINT/browse/$issue
+EOF
+
+###############################################################################
+## Export the issues in each format
+###############################################################################
+RUNS $jira ls --output csv --columns key,Summary,status.name
+DIFF <<EOF
+key,Summary,status.name
+$issue,"commas, ""quotes"" | pipes",To Do
+EOF
+
+RUNS $jira ls --output markdown --columns key,summary,priority
+DIFF <<EOF
+| key | summary | priority |
+| --- | --- | --- |
+| $issue | commas, "quotes" \| pipes | Medium |
+EOF
+
+RUNS $jira ls --output jsonl --columns key,status.name --limit 0
+DIFF <<EOF
+{"key":"$issue","status.name":"To Do"}
+EOF
+
+RUNS $jira view $issue --output yaml --columns key,summary
+DIFF <<EOF
+---
+key: $issue
+summary: commas, "quotes" | pipes
+EOF
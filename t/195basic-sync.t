@@ -0,0 +1,67 @@
+#!/bin/bash
+eval "$(curl -q -s https://raw.githubusercontent.com/coryb/osht/master/osht.sh)"
+cd $(dirname $0)
+jira="../jira --project BASIC"
+export JIRA_LOG_FORMAT="%{level:-5s} %{message}"
+
+ENDPOINT="http://localhost:8080"
+if [ -n "$JIRACLOUD" ]; then
+    ENDPOINT="https://go-jira.atlassian.net"
+fi
+
+PLAN 11
+
+# reset login
+RUNS $jira logout
+RUNS $jira login
+
+# cleanup from previous failed test executions
+($jira ls | awk -F: '{print $1}' | while read issue; do ../jira done $issue; done) | sed 's/^/# CLEANUP: /g'
+
+cat > desired.yml <<YML
+project: BASIC
+issuetype: Bug
+issues:
+  - id: sync-test-$$
+    fields:
+      summary: desired summary
+YML
+
+###############################################################################
+## Plan the creation of an issue
+###############################################################################
+RUNS $jira plan desired.yml
+DIFF <<EOF
++ create sync-test-$$ (BASIC Bug)
+    Issue Type (issuetype): "Bug"
+    Labels (labels): "sync:sync-test-$$"
+    Project (project): "BASIC"
+    Summary (summary): "desired summary"
+Plan: 1 to create, 0 to update, 0 to transition, 0 links to add
+EOF
+
+###############################################################################
+## Apply it, then nothing is left to do
+###############################################################################
+RUNS $jira apply desired.yml --yes
+issue=$(awk '/^OK/{print $2}' $OSHT_STDOUT)
+GREP "^OK $issue $ENDPOINT/browse/$issue"
+
+RUNS $jira apply desired.yml --yes
+DIFF <<EOF
+No changes, the issues match the desired state
+EOF
+
+###############################################################################
+## Change the summary, only the summary is updated
+###############################################################################
+sed -i.bak 's/desired summary/changed summary/' desired.yml
+RUNS $jira plan desired.yml
+DIFF <<EOF
+~ update sync-test-$$ $issue
+    Summary (summary): "desired summary" -> "changed summary"
+Plan: 0 to create, 1 to update, 0 to transition, 0 links to add
+EOF
+rm -f desired.yml desired.yml.bak
+
+RUNS $jira done $issue
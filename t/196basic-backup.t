@@ -0,0 +1,42 @@
+#!/bin/bash
+eval "$(curl -q -s https://raw.githubusercontent.com/coryb/osht/master/osht.sh)"
+cd $(dirname $0)
+jira="../jira --project BASIC"
+export JIRA_LOG_FORMAT="%{level:-5s} %{message}"
+
+ENDPOINT="http://localhost:8080"
+if [ -n "$JIRACLOUD" ]; then
+    ENDPOINT="https://go-jira.atlassian.net"
+fi
+
+PLAN 9
+
+# reset login
+RUNS $jira logout
+RUNS $jira login
+
+# cleanup from previous failed test executions
+($jira ls | awk -F: '{print $1}' | while read issue; do ../jira done $issue; done) | sed 's/^/# CLEANUP: /g'
+rm -rf backup
+
+###############################################################################
+## Create an issue with a comment to back up
+###############################################################################
+RUNS $jira create -o summary=backup -o priority=Low --noedit
+issue=$(awk '{print $2}' $OSHT_STDOUT)
+RUNS $jira comment $issue -m "backup comment" --noedit
+
+###############################################################################
+## Back up the project, the issue is written with its comments
+###############################################################################
+RUNS $jira backup backup
+GREP "^OK $issue backup/issues/$issue.json$"
+RUNS grep -q '"backup comment"' backup/issues/$issue.json
+
+###############################################################################
+## Nothing has changed so the second backup does not write the issue again
+###############################################################################
+RUNS $jira backup backup
+NGREP "^OK $issue "
+
+rm -rf backup
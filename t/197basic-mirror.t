@@ -0,0 +1,51 @@
+#!/bin/bash
+eval "$(curl -q -s https://raw.githubusercontent.com/coryb/osht/master/osht.sh)"
+cd $(dirname $0)
+jira="../jira --project BASIC"
+export JIRA_LOG_FORMAT="%{level:-5s} %{message}"
+
+ENDPOINT="http://localhost:8080"
+if [ -n "$JIRACLOUD" ]; then
+    ENDPOINT="https://go-jira.atlassian.net"
+fi
+
+PLAN 10
+
+# reset login
+RUNS $jira logout
+RUNS $jira login
+
+# cleanup from previous failed test executions
+($jira ls | awk -F: '{print $1}' | while read issue; do ../jira done $issue; done) | sed 's/^/# CLEANUP: /g'
+
+cat > mirror.yml <<YML
+issuetypes:
+  Bug: Task
+YML
+
+###############################################################################
+## Create an issue with a comment to mirror
+###############################################################################
+RUNS $jira create -o summary=mirror -o priority=Low --noedit
+issue=$(awk '{print $2}' $OSHT_STDOUT)
+RUNS $jira comment $issue -m "mirror comment" --noedit
+
+###############################################################################
+## Mirror the issue to the PROJECT project of the "mirror" profile
+###############################################################################
+RUNS $jira mirror --to mirror --mapping mirror.yml -q "key = $issue"
+mirrored=$(awk '{print $3}' $OSHT_STDOUT)
+DIFF <<EOF
+OK $issue $mirrored $ENDPOINT/browse/$mirrored
+EOF
+
+RUNS ../jira comments $mirrored
+GREP "Mirrored from $issue, "
+
+###############################################################################
+## Nothing has changed so mirroring again does nothing
+###############################################################################
+RUNS $jira mirror --to mirror --mapping mirror.yml -q "key = $issue"
+DIFF </dev/null
+
+rm -f mirror.yml
@@ -0,0 +1,62 @@
+#!/bin/bash
+eval "$(curl -q -s https://raw.githubusercontent.com/coryb/osht/master/osht.sh)"
+cd $(dirname $0)
+jira="../jira --project BASIC"
+export JIRA_LOG_FORMAT="%{level:-5s} %{message}"
+
+ENDPOINT="http://localhost:8080"
+if [ -n "$JIRACLOUD" ]; then
+    ENDPOINT="https://go-jira.atlassian.net"
+fi
+
+PLAN 15
+
+# reset login
+RUNS $jira logout
+RUNS $jira login
+
+# cleanup from previous failed test executions
+($jira ls | awk -F: '{print $1}' | while read issue; do ../jira done $issue; done) | sed 's/^/# CLEANUP: /g'
+
+###############################################################################
+## Create an issue and sync it to the offline cache
+###############################################################################
+RUNS $jira create -o summary=offline -o priority=Low --noedit
+issue=$(awk '{print $2}' $OSHT_STDOUT)
+
+RUNS $jira sync -q "key = $issue"
+DIFF <<EOF
+OK 1 issues updated, 1 cached for: key = $issue
+EOF
+
+###############################################################################
+## View and list the issue from the cache
+###############################################################################
+RUNS $jira view $issue --offline
+GREP "^cached: a minute ago$"
+
+RUNS $jira ls -q "key = $issue" --offline
+DIFF <<EOF
+$(printf %-12s $issue:) offline
+(cached a minute ago)
+EOF
+
+###############################################################################
+## Queries that were never synced are not available offline
+###############################################################################
+NRUNS $jira ls -q "key = $issue ORDER BY key" --offline
+
+###############################################################################
+## Syncing again drops the issues the query no longer returns
+###############################################################################
+RUNS $jira sync -q "key = $issue AND resolution is EMPTY"
+DIFF <<EOF
+OK 1 issues updated, 1 cached for: key = $issue AND resolution is EMPTY
+EOF
+
+RUNS $jira done $issue
+
+RUNS $jira sync -q "key = $issue AND resolution is EMPTY"
+DIFF <<EOF
+OK 0 issues updated, 0 cached for: key = $issue AND resolution is EMPTY
+EOF
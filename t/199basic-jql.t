@@ -0,0 +1,97 @@
+#!/bin/bash
+eval "$(curl -q -s https://raw.githubusercontent.com/coryb/osht/master/osht.sh)"
+cd $(dirname $0)
+jira="../jira --project BASIC"
+export JIRA_LOG_FORMAT="%{level:-5s} %{message}"
+
+ENDPOINT="http://localhost:8080"
+if [ -n "$JIRACLOUD" ]; then
+    ENDPOINT="https://go-jira.atlassian.net"
+fi
+
+PLAN 25
+
+# reset login
+RUNS $jira logout
+RUNS $jira login
+
+# cleanup from previous failed test executions
+($jira ls | awk -F: '{print $1}' | while read issue; do ../jira done $issue; done) | sed 's/^/# CLEANUP: /g'
+
+###############################################################################
+## Create two issues and sync the open issues to the offline cache
+###############################################################################
+RUNS $jira create -o summary="jql one" -o priority=Low --noedit
+one=$(awk '{print $2}' $OSHT_STDOUT)
+RUNS $jira create -o summary="jql two" -o priority=High --noedit
+two=$(awk '{print $2}' $OSHT_STDOUT)
+
+RUNS $jira sync -q "project = BASIC AND resolution = unresolved"
+
+###############################################################################
+## Queries that were not synced are evaluated against the cached issues
+###############################################################################
+RUNS $jira ls --offline -q "project = BASIC AND summary ~ jql ORDER BY priority DESC"
+DIFF <<EOF
+$(printf %-12s $two:) jql two
+$(printf %-12s $one:) jql one
+(cached a minute ago)
+EOF
+
+RUNS $jira ls --offline -q "summary ~ 'jql' AND priority in (Low, Lowest) AND assignee = currentUser()"
+DIFF <<EOF
+$(printf %-12s $one:) jql one
+(cached a minute ago)
+EOF
+
+###############################################################################
+## Operators that need the issue history are not supported offline
+###############################################################################
+NRUNS $jira ls --offline -q "status WAS Done"
+
+###############################################################################
+## The query options are quoted and the sort order is validated
+###############################################################################
+RUNS $jira ls --assignee gojira --sort "priority desc, key"
+DIFF <<EOF
+$(printf %-12s $two:) jql two
+$(printf %-12s $one:) jql one
+EOF
+
+NRUNS $jira ls --sort "key, summary ~ jql"
+EDIFF <<EOF
+ERROR Invalid sort order 'key, summary ~ jql'
+ERROR Invalid sort order 'key, summary ~ jql'
+EOF
+
+###############################################################################
+## The query options compose into the generated query
+###############################################################################
+RUNS $jira ls --status "To Do" --priority Low,Lowest --created-since 1d --text jql
+DIFF <<EOF
+$(printf %-12s $one:) jql one
+EOF
+
+RUNS $jira ls --mine --priority High --priority Low --sort key
+DIFF <<EOF
+$(printf %-12s $one:) jql one
+$(printf %-12s $two:) jql two
+EOF
+
+RUNS $jira done $one
+RUNS $jira ls --mine --text jql --sort key
+DIFF <<EOF
+$(printf %-12s $two:) jql two
+EOF
+
+RUNS $jira ls --mine --text jql --include-resolved --sort key
+DIFF <<EOF
+$(printf %-12s $one:) jql one
+$(printf %-12s $two:) jql two
+EOF
+
+NRUNS $jira ls --created-since yesterday
+EDIFF <<EOF
+ERROR Invalid since "yesterday", expected a duration like 7d or a date like 2006-01-02
+ERROR Invalid since "yesterday", expected a duration like 7d or a date like 2006-01-02
+EOF
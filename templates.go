@@ -17,15 +17,22 @@ var allTemplates = map[string]string{
 	"create":         defaultCreateTemplate,
 	"subtask":        defaultSubtaskTemplate,
 	"comment":        defaultCommentTemplate,
+	"comments":       defaultCommentsTemplate,
 	"transition":     defaultTransitionTemplate,
 	"request":        defaultDebugTemplate,
 	"worklog":        defaultWorklogTemplate,
 	"worklogs":       defaultWorklogsTemplate,
+	"attachments":    defaultAttachmentsTemplate,
+	"history":        defaultHistoryTemplate,
+	"links":          defaultLinksTemplate,
+	"remotelinks":    defaultRemoteLinksTemplate,
+	"move":           defaultMoveTemplate,
+	"workflow":       defaultWorkflowTemplate,
 }
 
 const defaultDebugTemplate = "{{ . | toJson}}\n"
 
-const defaultListTemplate = "{{ range .issues }}{{ .key | append \":\" | printf \"%-12s\"}} {{ .fields.summary }}\n{{ end }}"
+const defaultListTemplate = "{{ range .issues }}{{ .key | append \":\" | printf \"%-12s\"}} {{ .fields.summary }}\n{{ end }}{{ if .cached }}(cached {{ .cached | age }} ago)\n{{ end }}"
 
 const defaultTableTemplate = `+{{ "-" | rep 16 }}+{{ "-" | rep 57 }}+{{ "-" | rep 14 }}+{{ "-" | rep 14 }}+{{ "-" | rep 12 }}+{{ "-" | rep 14 }}+{{ "-" | rep 14 }}+
 | {{ "Issue" | printf "%-14s" }} | {{ "Summary" | printf "%-55s" }} | {{ "Priority" | printf "%-12s" }} | {{ "Status" | printf "%-12s" }} | {{ "Age" | printf "%-10s" }} | {{ "Reporter" | printf "%-12s" }} | {{ "Assignee" | printf "%-12s" }} |
@@ -38,6 +45,9 @@ const defaultTableTemplate = `+{{ "-" | rep 16 }}+{{ "-" | rep 57 }}+{{ "-" | re
 
 const defaultViewTemplate = `{{/* view template */ -}}
 issue: {{ .key }}
+{{if .cached -}}
+cached: {{ .cached | age }} ago
+{{end -}}
 {{if .fields.created -}}
 created: {{ .fields.created | age }} ago
 {{end -}}
@@ -63,6 +73,11 @@ watchers: {{ range .fields.customfield_10110 }}{{ .name }} {{end}}
 blockers: {{ range .fields.issuelinks }}{{if .outwardIssue}}{{ .outwardIssue.key }}[{{.outwardIssue.fields.status.name}}]{{end}}{{end}}
 depends: {{ range .fields.issuelinks }}{{if .inwardIssue}}{{ .inwardIssue.key }}[{{.inwardIssue.fields.status.name}}]{{end}}{{end}}
 {{end -}}
+{{if .remotelinks -}}
+links:
+{{ range .remotelinks }}  - {{ .object.title }}: {{ .object.url }}
+{{end -}}
+{{end -}}
 {{if .fields.priority -}}
 priority: {{ .fields.priority.name }}
 {{end -}}
@@ -165,10 +180,26 @@ fields:
   parent:
     key: {{ .parent.key }}`
 
-const defaultCommentTemplate = `body: |~
-  {{ or .overrides.comment "" | indent 2 }}
+const defaultCommentTemplate = `{{/* comment template */ -}}
+{{if .issue}}# issue: {{ .issue }}
+{{end -}}
+body: |~
+  {{ or .overrides.comment (or .body "") | indent 2 }}
+{{if .visibility -}}
+visibility:
+  type: {{ .visibility.type }}
+  value: {{ .visibility.value }}
+{{end -}}
 `
 
+const defaultCommentsTemplate = `{{/* comments template */ -}}
+{{ range .comments }}- id: {{ .id }} # {{.author.name}}, {{.created | age}} ago{{if .visibility}}
+  visibility: {{ .visibility.type }}:{{ .visibility.value }}{{end}}
+  body: |
+    {{ or .body "" | indent 4 }}
+
+{{end}}`
+
 const defaultTransitionTemplate = `{{/* transition template */ -}}
 update:
   comment:
@@ -232,14 +263,51 @@ transition:
 const defaultWorklogTemplate = `{{/* worklog template */ -}}
 # issue: {{ .issue }}
 comment: |~
-  {{ or .comment "" }}
+  {{ or .comment "" | indent 2 }}
 timeSpent: {{ or .timeSpent "" }}
-started:
+started: {{ or .started "" }}
 `
 
 const defaultWorklogsTemplate = `{{/* worklogs template */ -}}
-{{ range .worklogs }}- # {{.author.name}}, {{.created | age}} ago
+{{ range .worklogs }}- id: {{ .id }} # {{.author.name}}, {{.created | age}} ago
   comment: {{ or .comment "" }}
   timeSpent: {{ .timeSpent }}
 
 {{end}}`
+
+const defaultAttachmentsTemplate = `{{/* attachments template */ -}}
+{{ range .fields.attachment }}{{ .id | append ":" | printf "%-8s" }} {{ .filename }} ({{ .size | size }}) # {{ .author.name }}, {{ .created | age }} ago
+{{end}}`
+
+const defaultHistoryTemplate = `{{/* history template */ -}}
+{{ range .changelog.histories }}- # {{ .author.name }}, {{ .created | age }} ago
+{{ range .items }}  {{ .field }}:{{ if .diff }} |
+    {{ .diff | indent 4 }}{{ else }} {{ or .fromString "" }} -> {{ or .toString "" }}{{ end }}
+{{ end }}
+{{ end }}`
+
+const defaultLinksTemplate = `{{/* links template */ -}}
+{{ range .links }}{{ .description }}:
+{{ range .links }}  {{ .issue.key | printf "%-12s" }} [{{ .issue.fields.status.name }}] {{ .issue.fields.summary }}
+{{ end }}{{ end }}`
+
+const defaultRemoteLinksTemplate = `{{/* remotelinks template */ -}}
+{{ range . }}{{ printf "%.0f:" .id | printf "%-8s" }} {{ .object.title }} {{ .object.url }}
+{{ end }}`
+
+const defaultMoveTemplate = `{{/* move template */ -}}
+# move {{ .source.key }} to {{ .project }} {{ .issuetype }}
+{{- if .statuses }}
+# statuses: {{ range $i, $s := .statuses }}{{ if $i }}, {{ end }}{{ $s }}{{ end }}
+{{- end }}
+status: {{ .status }}
+{{- range $name, $desc := .required }}
+# {{ $name }}: {{ $desc }} (required)
+{{- end }}
+fields:
+  {{ .fields | toYaml | indent 2 }}
+`
+
+const defaultWorkflowTemplate = `{{/* workflow template */ -}}
+{{ . | toYaml }}
+`
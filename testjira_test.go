@@ -0,0 +1,99 @@
+package jira
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+)
+
+// testJiraHandler will answer a request to the fake jira with a status code
+// and a response that is encoded as json, body is the decoded request body
+type testJiraHandler func(r *http.Request, body interface{}) (int, interface{})
+
+// testJira is a fake jira server for the tests that need to make requests.
+// Requests are answered by the handler for "METHOD /path" and recorded, so
+// the tests can check what was sent.  HOME is a temporary directory until
+// the server is closed, so nothing is written to the real ~/.jira.d.
+type testJira struct {
+	*httptest.Server
+	t        *testing.T
+	home     string
+	oldHome  string
+	lock     sync.Mutex
+	handlers map[string]testJiraHandler
+	requests []testJiraRequest
+}
+
+type testJiraRequest struct {
+	route string
+	body  interface{}
+}
+
+func newTestJira(t *testing.T, handlers map[string]testJiraHandler) *testJira {
+	home, err := ioutil.TempDir("", "jira-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &testJira{t: t, handlers: handlers, home: home, oldHome: os.Getenv("HOME")}
+	os.Setenv("HOME", home)
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serve))
+	return server
+}
+
+// Close will stop the server and restore HOME
+func (s *testJira) Close() {
+	s.Server.Close()
+	os.Setenv("HOME", s.oldHome)
+	os.RemoveAll(s.home)
+}
+
+func (s *testJira) serve(w http.ResponseWriter, r *http.Request) {
+	route := r.Method + " " + r.URL.Path
+	var body interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	s.lock.Lock()
+	s.requests = append(s.requests, testJiraRequest{route: route, body: body})
+	handler, ok := s.handlers[route]
+	s.lock.Unlock()
+	if !ok {
+		s.t.Errorf("unexpected request %s", route)
+		w.WriteHeader(404)
+		return
+	}
+	status, response := handler(r, body)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if response != nil {
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// cli will return a client for the fake jira with the options
+func (s *testJira) cli(opts map[string]interface{}) *Cli {
+	opts["endpoint"] = s.URL
+	return New(opts)
+}
+
+// sent will return the bodies of the requests made to the route
+func (s *testJira) sent(route string) []interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	bodies := []interface{}{}
+	for _, request := range s.requests {
+		if request.route == route {
+			bodies = append(bodies, request.body)
+		}
+	}
+	return bodies
+}
+
+// testJiraRespond is a handler always answering with the status and response
+func testJiraRespond(status int, response interface{}) testJiraHandler {
+	return func(*http.Request, interface{}) (int, interface{}) {
+		return status, response
+	}
+}
@@ -12,7 +12,9 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
@@ -94,8 +96,62 @@ func copyFile(src, dst string) (err error) {
 	return
 }
 
+// jiraTimeFormat is the timestamp format used in jira responses and expected
+// in requests (like the worklog "started" field)
+const jiraTimeFormat = "2006-01-02T15:04:05.000-0700"
+
+var durationRegexp = regexp.MustCompile(`^\s*(\d+(\.\d+)?\s*[wdhm]\s*)+$`)
+
+// validateDuration will verify the value is in the jira duration format
+// like "1w 2d 3h 30m", or a bare number of minutes
+func validateDuration(name, value string) error {
+	if durationRegexp.MatchString(value) {
+		return nil
+	}
+	if _, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		return nil
+	}
+	err := fmt.Errorf("Invalid %s %q, expected a duration like \"1h 30m\" using w, d, h, m units", name, value)
+	log.Errorf("%s", err)
+	return err
+}
+
+var relativeDurationRegexp = regexp.MustCompile(`^(\d+)([wdhm])$`)
+
+// parseRelativeDuration will parse durations like "7d", "2w", "12h" or "30m"
+func parseRelativeDuration(value string) (time.Duration, error) {
+	match := relativeDurationRegexp.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, fmt.Errorf("Invalid duration %q, expected a duration like 7d, 2w, 12h or 30m", value)
+	}
+	count, _ := strconv.Atoi(match[1])
+	unit := map[string]time.Duration{
+		"w": 7 * 24 * time.Hour,
+		"d": 24 * time.Hour,
+		"h": time.Hour,
+		"m": time.Minute,
+	}[match[2]]
+	return time.Duration(count) * unit, nil
+}
+
+// parseSince will return the time for values that are either a relative
+// duration (like "7d") before now or a date like "2017-01-31"
+func parseSince(value string) (time.Time, error) {
+	if d, err := parseRelativeDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, format := range []string{"2006-01-02", "2006-01-02 15:04", time.RFC3339, jiraTimeFormat} {
+		if t, err := time.ParseInLocation(format, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	err := fmt.Errorf("Invalid since %q, expected a duration like 7d or a date like 2006-01-02", value)
+	log.Errorf("%s", err)
+	return time.Time{}, err
+}
+
 func fuzzyAge(start string) (string, error) {
-	t, err := time.Parse("2006-01-02T15:04:05.000-0700", start)
+	t, err := time.Parse(jiraTimeFormat, start)
 	if err != nil {
 		return "", err
 	}
@@ -114,6 +170,19 @@ func fuzzyAge(start string) (string, error) {
 	return fmt.Sprintf("%d days", int(delta.Hours()/24)), nil
 }
 
+func humanSize(size float64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	unit := 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d%s", int(size), units[unit])
+	}
+	return fmt.Sprintf("%.1f%s", size, units[unit])
+}
+
 func dateFormat(format string, content string) (string, error) {
 	t, err := time.Parse("2006-01-02T15:04:05.000-0700", content)
 	if err != nil {
@@ -142,6 +211,13 @@ func runTemplate(templateContent string, data interface{}, out io.Writer) error
 			}
 			return string(bytes), nil
 		},
+		"toYaml": func(content interface{}) (string, error) {
+			bytes, err := yaml.Marshal(content)
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimSuffix(string(bytes), "\n"), nil
+		},
 		"append": func(more string, content interface{}) (string, error) {
 			switch value := content.(type) {
 			case string:
@@ -209,6 +285,9 @@ func runTemplate(templateContent string, data interface{}, out io.Writer) error
 		"dateFormat": func(format string, content string) (string, error) {
 			return dateFormat(format, content)
 		},
+		"size": func(content float64) string {
+			return humanSize(content)
+		},
 	}
 	tmpl, err := template.New("template").Funcs(funcs).Parse(templateContent)
 	if err != nil {
@@ -307,6 +386,37 @@ func promptYN(prompt string, yes bool) bool {
 	return false
 }
 
+// promptChoice will show the numbered choices and return the index of the
+// one selected by the user
+func promptChoice(prompt string, choices []string) (int, error) {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Printf("%s:\n", prompt)
+	for i, choice := range choices {
+		fmt.Printf("  %d) %s\n", i+1, choice)
+	}
+	for {
+		fmt.Printf("Select [1-%d]: ", len(choices))
+		text, err := reader.ReadString('\n')
+		ans := strings.TrimSpace(text)
+		if ans == "" && err != nil {
+			return 0, fmt.Errorf("Nothing selected")
+		}
+		if n, err := strconv.Atoi(ans); err == nil && n >= 1 && n <= len(choices) {
+			return n - 1, nil
+		}
+	}
+}
+
+// isTerminal will return true if the file is a terminal rather than a pipe
+// or regular file
+func isTerminal(fh *os.File) bool {
+	stat, err := fh.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
 func yamlFixup(data interface{}) (interface{}, error) {
 	switch d := data.(type) {
 	case map[interface{}]interface{}:
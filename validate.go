@@ -0,0 +1,190 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/Netflix-Skunkworks/go-jira.v0/data"
+)
+
+// fieldMetaMap will decode the "fields" of the createmeta, editmeta or
+// transition meta
+func fieldMetaMap(meta map[string]interface{}) jiradata.FieldMetaMap {
+	metaFields := jiradata.FieldMetaMap{}
+	if content, err := jsonEncode(meta["fields"]); err == nil {
+		json.Unmarshal([]byte(content), &metaFields)
+	}
+	return metaFields
+}
+
+func fieldDisplayName(name string, meta *jiradata.FieldMeta) string {
+	if meta != nil && meta.Name != "" && meta.Name != name {
+		return fmt.Sprintf("%s (%s)", meta.Name, name)
+	}
+	return name
+}
+
+func allowedValueNames(allowed jiradata.AllowedValues) string {
+	names := []string{}
+	for _, a := range allowed {
+		if value, ok := a.(map[string]interface{}); ok {
+			for _, key := range []string{"name", "value", "key", "id"} {
+				if v, ok := value[key]; ok {
+					names = append(names, fmt.Sprintf("%v", v))
+					break
+				}
+			}
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// allowedValue will return true if the value (or every item when it is a
+// list) matches one of the allowed values
+func allowedValue(value interface{}, allowed jiradata.AllowedValues) (bool, interface{}) {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if ok, bad := allowedValue(item, allowed); !ok {
+				return false, bad
+			}
+		}
+		return true, nil
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return true, nil
+		}
+		if findAllowedValue(v, allowed) != nil {
+			return true, nil
+		}
+		for _, key := range []string{"name", "value", "key", "id"} {
+			if bad, ok := v[key]; ok {
+				return false, bad
+			}
+		}
+		return false, v
+	default:
+		for _, a := range allowed {
+			if av, ok := a.(map[string]interface{}); ok {
+				for _, key := range []string{"id", "key", "name", "value"} {
+					if av[key] == value {
+						return true, nil
+					}
+				}
+			}
+		}
+		return false, value
+	}
+}
+
+// validateEdit will check the edited document against the field metadata
+// from createmeta, editmeta or the transition meta.  Every problem found is
+// returned so they can all be reported at once.  Required fields are only
+// checked for being missing entirely when the document is creating an issue
+// (ie it sets the project), otherwise required fields in the document must
+// not be empty.
+func validateEdit(edited map[string]interface{}, metaFields jiradata.FieldMetaMap) []string {
+	problems := []string{}
+	fields, _ := edited["fields"].(map[string]interface{})
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := fields[name]
+		fieldMeta, ok := metaFields[name]
+		if !ok || fieldMeta == nil {
+			problems = append(problems, fmt.Sprintf("Field %s is not editable", name))
+			continue
+		}
+		display := fieldDisplayName(name, fieldMeta)
+		if len(fieldMeta.Operations) > 0 && !hasOperation(fieldMeta.Operations, "set") {
+			problems = append(problems, fmt.Sprintf("%s cannot be set, allowed operations: %s", display, strings.Join(fieldMeta.Operations, ", ")))
+			continue
+		}
+		if isEmptyValue(value) || isEmptyValue(identifyingEmpty(value)) {
+			if fieldMeta.Required && !fieldMeta.HasDefaultValue {
+				problems = append(problems, fmt.Sprintf("%s is required", display))
+			}
+			continue
+		}
+		if len(fieldMeta.AllowedValues) > 0 {
+			if ok, bad := allowedValue(value, fieldMeta.AllowedValues); !ok {
+				problems = append(problems, fmt.Sprintf("Invalid value %q for %s, allowed: %s", fmt.Sprintf("%v", bad), display, allowedValueNames(fieldMeta.AllowedValues)))
+			}
+		}
+	}
+
+	update, _ := edited["update"].(map[string]interface{})
+	for name, ops := range update {
+		fieldMeta, ok := metaFields[name]
+		if !ok || fieldMeta == nil || len(fieldMeta.Operations) == 0 {
+			continue
+		}
+		opList, _ := ops.([]interface{})
+		for _, op := range opList {
+			opMap, _ := op.(map[string]interface{})
+			for operation := range opMap {
+				if !hasOperation(fieldMeta.Operations, operation) {
+					problems = append(problems, fmt.Sprintf("Operation %s not allowed for %s, allowed operations: %s", operation, fieldDisplayName(name, fieldMeta), strings.Join(fieldMeta.Operations, ", ")))
+				}
+			}
+		}
+	}
+
+	if _, creating := fields["project"]; creating {
+		required := []string{}
+		for name, fieldMeta := range metaFields {
+			if fieldMeta == nil || !fieldMeta.Required || fieldMeta.HasDefaultValue {
+				continue
+			}
+			if _, ok := fields[name]; !ok {
+				required = append(required, fmt.Sprintf("%s is required", fieldDisplayName(name, fieldMeta)))
+			}
+		}
+		sort.Strings(required)
+		problems = append(problems, required...)
+	}
+	return problems
+}
+
+// identifyingEmpty will return nil for objects like {"name": ""} that the
+// templates produce for fields left blank
+func identifyingEmpty(value interface{}) interface{} {
+	if v, ok := value.(map[string]interface{}); ok {
+		for _, s := range v {
+			if !isEmptyValue(s) {
+				return value
+			}
+		}
+		return nil
+	}
+	return value
+}
+
+func hasOperation(operations jiradata.Operations, operation string) bool {
+	for _, op := range operations {
+		if op == operation {
+			return true
+		}
+	}
+	return false
+}
+
+// errorComments will return the content with the problems inserted as
+// YAML comments at the top, replacing the comments from a previous attempt
+func errorComments(content []byte, problems []string) []byte {
+	lines := strings.Split(string(content), "\n")
+	for len(lines) > 0 && strings.HasPrefix(lines[0], "# ERROR: ") {
+		lines = lines[1:]
+	}
+	comments := make([]string, 0, len(problems))
+	for _, problem := range problems {
+		comments = append(comments, fmt.Sprintf("# ERROR: %s", problem))
+	}
+	return []byte(strings.Join(append(comments, lines...), "\n"))
+}
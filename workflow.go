@@ -0,0 +1,269 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/Netflix-Skunkworks/go-jira.v0/data"
+)
+
+// WorkflowProfile holds the workflow specific settings for a project and
+// issuetype, configured in the 'workflows' option like:
+//
+//	workflows:
+//	  GOJIRA:
+//	    Bug:
+//	      transitions:
+//	        close: Close Issue
+//	        duplicate: Close Issue
+//	      defaultResolution: Fixed
+//	      duplicateResolution: Duplicate
+//	    default:
+//	      transitions:
+//	        in-progress: Start Progress
+//
+// Transitions maps the shortcut commands (and "duplicate", used when
+// closing duplicates) to the transition names of the workflow.  The
+// "default" profile of a project is used for issuetypes without a profile.
+type WorkflowProfile struct {
+	Transitions         map[string]string `json:"transitions,omitempty" yaml:"transitions,omitempty"`
+	DefaultResolution   string            `json:"defaultResolution,omitempty" yaml:"defaultResolution,omitempty"`
+	DuplicateResolution string            `json:"duplicateResolution,omitempty" yaml:"duplicateResolution,omitempty"`
+}
+
+// shortcutTransitions are the transition names used by the shortcut
+// commands when there is no workflow profile or alias for them
+var shortcutTransitions = map[string]string{
+	"todo":        "To Do",
+	"backlog":     "Backlog",
+	"done":        "Done",
+	"in-progress": "Progress",
+}
+
+// WorkflowProfile will return the workflow profile for the project and
+// issuetype from the 'workflows' option.  An empty profile is returned when
+// there is none configured.
+func (c *Cli) WorkflowProfile(project string, issuetype string) *WorkflowProfile {
+	profile := &WorkflowProfile{}
+	raw, ok := c.opts["workflows"]
+	if !ok {
+		return profile
+	}
+	fixed, err := yamlFixup(raw)
+	if err != nil {
+		log.Warningf("Invalid workflows option: %s", err)
+		return profile
+	}
+	workflows, _ := fixed.(map[string]interface{})
+	profiles, _ := workflows[project].(map[string]interface{})
+	config, ok := profiles[issuetype]
+	if !ok {
+		if config, ok = profiles["default"]; !ok {
+			return profile
+		}
+	}
+	content, err := jsonEncode(config)
+	if err != nil {
+		return profile
+	}
+	if err := json.Unmarshal([]byte(content), profile); err != nil {
+		log.Warningf("Invalid workflow profile for %s %s: %s", project, issuetype, err)
+	}
+	return profile
+}
+
+func (c *Cli) issueWorkflowProfile(issueData map[string]interface{}) *WorkflowProfile {
+	return c.WorkflowProfile(issueField(issueData, "project", "key"), issueField(issueData, "issuetype", "name"))
+}
+
+// CmdCloseDuplicate will close the issue as a duplicate, using the
+// "duplicate" transition and the duplicateResolution of the workflow profile
+// when configured.  Otherwise the issue is closed with the first of the
+// "close" or "done" transitions, or started then stopped when neither is
+// available.
+func (c *Cli) CmdCloseDuplicate(issue string) error {
+	log.Debugf("close duplicate called")
+	issueData, err := c.issueWithTransitions(issue)
+	if err != nil {
+		return err
+	}
+	profile := c.issueWorkflowProfile(issueData)
+
+	c.opts["resolution"] = "Duplicate"
+	if profile.DuplicateResolution != "" {
+		c.opts["resolution"] = profile.DuplicateResolution
+	}
+	if trans, ok := profile.Transitions["duplicate"]; ok {
+		return c.transition(issue, issueData, trans)
+	}
+
+	trans := issueTransitions(issueData)
+	if len(trans.Matches(c.transitionAlias(issueData, profile, "close"))) > 0 {
+		return c.transition(issue, issueData, "close")
+	} else if len(trans.Matches(c.transitionAlias(issueData, profile, "done"))) > 0 {
+		// for now just assume if there is no "close", then
+		// there is a "done" state
+		return c.transition(issue, issueData, "done")
+	} else if len(trans.Matches(c.transitionAlias(issueData, profile, "start"))) > 0 {
+		if err := c.transition(issue, issueData, "start"); err != nil {
+			return err
+		}
+		return c.CmdTransition(issue, "stop")
+	}
+	return nil
+}
+
+// discoveredTransition is a transition seen on an issue while discovering
+// the workflow of a project
+type discoveredTransition struct {
+	transition  *jiradata.Transition
+	resolutions []string
+}
+
+// CmdWorkflowDiscover will generate a starting workflow profile for each
+// issuetype of the project from the transitions available on existing issues
+// in each status, and send it to the "workflow" template.  The profile is
+// only a guess based on the transition names and status categories and
+// should be reviewed before adding it to config.yml.
+func (c *Cli) CmdWorkflowDiscover(project string) error {
+	log.Debugf("workflow discover called")
+	if project == "" {
+		err := fmt.Errorf("Missing required 'project' option")
+		log.Errorf("%s", err)
+		return err
+	}
+	uri := fmt.Sprintf("%s/rest/api/2/project/%s/statuses", c.endpoint, project)
+	data, err := responseToJSON(c.get(uri))
+	if err != nil {
+		return err
+	}
+	types, _ := data.([]interface{})
+
+	profiles := map[string]interface{}{}
+	for _, t := range types {
+		issueType, _ := t.(map[string]interface{})
+		issuetype, _ := issueType["name"].(string)
+		statuses, _ := issueType["statuses"].([]interface{})
+
+		discovered := []discoveredTransition{}
+		for _, s := range statuses {
+			status, _ := s.(map[string]interface{})["name"].(string)
+			sample, err := c.statusSample("", project, issuetype, status)
+			if err != nil {
+				return err
+			}
+			if sample == "" {
+				log.Debugf("No %s %s issue in status %s, skipping", project, issuetype, status)
+				continue
+			}
+			transitions, err := c.ValidTransitions(sample)
+			if err != nil {
+				return err
+			}
+			for _, trans := range transitions {
+				found := discoveredTransition{transition: trans}
+				if resolution, ok := trans.Fields["resolution"]; ok {
+					for _, value := range resolution.AllowedValues {
+						if allowed, ok := value.(map[string]interface{}); ok {
+							found.resolutions = append(found.resolutions, fmt.Sprintf("%v", allowed["name"]))
+						}
+					}
+				}
+				discovered = append(discovered, found)
+			}
+		}
+		if len(discovered) == 0 {
+			log.Warningf("No %s %s issues found, unable to discover workflow", project, issuetype)
+			continue
+		}
+		profiles[issuetype] = guessWorkflowProfile(discovered)
+	}
+
+	return runTemplate(c.getTemplate("workflow"), map[string]interface{}{
+		"workflows": map[string]interface{}{
+			project: profiles,
+		},
+	}, nil)
+}
+
+// guessWorkflowProfile will pick the transitions for the shortcut commands
+// from the names and target status categories of the discovered transitions
+func guessWorkflowProfile(discovered []discoveredTransition) *WorkflowProfile {
+	profile := &WorkflowProfile{
+		Transitions: map[string]string{},
+	}
+	guesses := []struct {
+		shortcut string
+		category string // target status category key, empty for any
+		status   string // target status name, empty for any
+		word     string // word the transition name must contain, empty for any
+	}{
+		{"close", "done", "", "close"},
+		{"close", "done", "", ""},
+		{"resolve", "done", "", "resolve"},
+		{"done", "", "done", ""},
+		{"done", "done", "", ""},
+		{"start", "indeterminate", "", "start"},
+		{"start", "indeterminate", "", ""},
+		{"in-progress", "indeterminate", "", ""},
+		{"stop", "new", "", "stop"},
+		{"todo", "", "to do", ""},
+		{"todo", "new", "", ""},
+		{"backlog", "", "backlog", ""},
+		{"reopen", "", "", "reopen"},
+		{"acknowledge", "", "", "ack"},
+	}
+	for _, guess := range guesses {
+		if _, ok := profile.Transitions[guess.shortcut]; ok {
+			continue
+		}
+		for _, d := range discovered {
+			trans := d.transition
+			if trans.To == nil {
+				continue
+			}
+			if guess.category != "" && (trans.To.StatusCategory == nil || trans.To.StatusCategory.Key != guess.category) {
+				continue
+			}
+			if guess.status != "" && !strings.EqualFold(trans.To.Name, guess.status) {
+				continue
+			}
+			if guess.word != "" && !strings.Contains(strings.ToLower(trans.Name), guess.word) {
+				continue
+			}
+			profile.Transitions[guess.shortcut] = trans.Name
+			break
+		}
+	}
+	for _, d := range discovered {
+		if hasResolution(d.resolutions, "duplicate") != "" {
+			profile.Transitions["duplicate"] = d.transition.Name
+			break
+		}
+	}
+
+	resolutions := []string{}
+	for _, d := range discovered {
+		resolutions = append(resolutions, d.resolutions...)
+	}
+	sort.Strings(resolutions)
+	if resolution := hasResolution(resolutions, "fixed"); resolution != "" {
+		profile.DefaultResolution = resolution
+	} else if resolution := hasResolution(resolutions, "done"); resolution != "" {
+		profile.DefaultResolution = resolution
+	}
+	profile.DuplicateResolution = hasResolution(resolutions, "duplicate")
+	return profile
+}
+
+// hasResolution will return the first resolution containing the word
+func hasResolution(resolutions []string, word string) string {
+	for _, resolution := range resolutions {
+		if strings.Contains(strings.ToLower(resolution), word) {
+			return resolution
+		}
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package jira
+
+import (
+	"testing"
+
+	"gopkg.in/Netflix-Skunkworks/go-jira.v0/data"
+)
+
+func testWorkflowCli() *Cli {
+	return &Cli{opts: map[string]interface{}{
+		"workflows": map[interface{}]interface{}{
+			"GOJIRA": map[interface{}]interface{}{
+				"Bug": map[interface{}]interface{}{
+					"transitions": map[interface{}]interface{}{
+						"close": "Close Issue",
+					},
+					"defaultResolution": "Fixed",
+				},
+				"default": map[interface{}]interface{}{
+					"transitions": map[interface{}]interface{}{
+						"close": "Done",
+					},
+				},
+			},
+		},
+		"transitionAliases": map[interface{}]interface{}{
+			"GOJIRA": map[interface{}]interface{}{
+				"start": "Begin Work",
+			},
+		},
+	}}
+}
+
+func testIssue(project, issuetype string) map[string]interface{} {
+	return map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":   map[string]interface{}{"key": project},
+			"issuetype": map[string]interface{}{"name": issuetype},
+		},
+	}
+}
+
+func TestWorkflowProfile(t *testing.T) {
+	c := testWorkflowCli()
+	profile := c.WorkflowProfile("GOJIRA", "Bug")
+	if profile.Transitions["close"] != "Close Issue" || profile.DefaultResolution != "Fixed" {
+		t.Errorf("expected the Bug profile, got %#v", profile)
+	}
+	if profile := c.WorkflowProfile("GOJIRA", "Task"); profile.Transitions["close"] != "Done" {
+		t.Errorf("expected the default profile for Task, got %#v", profile)
+	}
+	if profile := c.WorkflowProfile("OTHER", "Bug"); len(profile.Transitions) != 0 {
+		t.Errorf("expected an empty profile for OTHER, got %#v", profile)
+	}
+}
+
+func TestTransitionAlias(t *testing.T) {
+	c := testWorkflowCli()
+	bug := testIssue("GOJIRA", "Bug")
+	profile := c.issueWorkflowProfile(bug)
+	for trans, expected := range map[string]string{
+		// the workflow profile comes first
+		"close": "Close Issue",
+		// then the transitionAliases of the project
+		"start": "Begin Work",
+		// then the names for the shortcut commands
+		"in-progress": "Progress",
+		"todo":        "To Do",
+		// anything else is used as is
+		"Reopen Issue": "Reopen Issue",
+	} {
+		if alias := c.transitionAlias(bug, profile, trans); alias != expected {
+			t.Errorf("expected %q for %q, got %q", expected, trans, alias)
+		}
+	}
+
+	other := testIssue("OTHER", "Bug")
+	if alias := c.transitionAlias(other, c.issueWorkflowProfile(other), "start"); alias != "start" {
+		t.Errorf("expected no alias for start in OTHER, got %q", alias)
+	}
+}
+
+func discovered(name, status, category string, resolutions ...string) discoveredTransition {
+	return discoveredTransition{
+		transition: &jiradata.Transition{
+			Name: name,
+			To: &jiradata.Status{
+				Name:           status,
+				StatusCategory: &jiradata.StatusCategory{Key: category},
+			},
+		},
+		resolutions: resolutions,
+	}
+}
+
+func TestGuessWorkflowProfile(t *testing.T) {
+	profile := guessWorkflowProfile([]discoveredTransition{
+		discovered("Start Progress", "In Progress", "indeterminate"),
+		discovered("Stop Progress", "To Do", "new"),
+		discovered("Resolve Issue", "Resolved", "done", "Fixed", "Duplicate", "Won't Fix"),
+		discovered("Close Issue", "Closed", "done", "Fixed", "Duplicate"),
+		discovered("Reopen Issue", "Reopened", "new"),
+	})
+	expected := map[string]string{
+		"close":       "Close Issue",
+		"resolve":     "Resolve Issue",
+		"done":        "Resolve Issue",
+		"start":       "Start Progress",
+		"in-progress": "Start Progress",
+		"stop":        "Stop Progress",
+		"todo":        "Stop Progress",
+		"reopen":      "Reopen Issue",
+		"duplicate":   "Resolve Issue",
+	}
+	for shortcut, name := range expected {
+		if profile.Transitions[shortcut] != name {
+			t.Errorf("expected %q for %s, got %q", name, shortcut, profile.Transitions[shortcut])
+		}
+	}
+	for shortcut, name := range profile.Transitions {
+		if _, ok := expected[shortcut]; !ok {
+			t.Errorf("unexpected transition %q for %s", name, shortcut)
+		}
+	}
+	if profile.DefaultResolution != "Fixed" || profile.DuplicateResolution != "Duplicate" {
+		t.Errorf("expected Fixed and Duplicate resolutions, got %q and %q", profile.DefaultResolution, profile.DuplicateResolution)
+	}
+}
+
+func TestGuessWorkflowProfileDoneResolution(t *testing.T) {
+	profile := guessWorkflowProfile([]discoveredTransition{
+		discovered("Done", "Done", "done", "Done", "Won't Do"),
+	})
+	if profile.Transitions["close"] != "Done" || profile.Transitions["done"] != "Done" {
+		t.Errorf("expected Done for close and done, got %#v", profile.Transitions)
+	}
+	if profile.DefaultResolution != "Done" || profile.DuplicateResolution != "" {
+		t.Errorf("expected the Done resolution and no duplicate resolution, got %q and %q", profile.DefaultResolution, profile.DuplicateResolution)
+	}
+}